@@ -0,0 +1,142 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AccessTokenType задает тип токена доступа в заголовке (RFC 9068,
+// раздел 2.1).
+const AccessTokenType = "at+jwt"
+
+// accessTokenClaims содержит список обязательных полей токена доступа
+// (RFC 9068, раздел 2.2).
+var accessTokenClaims = []string{"iss", "exp", "aud", "sub", "client_id", "iat", "jti"}
+
+// AccessToken описывает содержимое токена доступа в формате JWT (RFC 9068,
+// раздел 2.2).
+type AccessToken struct {
+	Claims
+	ClientID     string        `json:"client_id"`              // идентификатор клиента
+	Scope        string        `json:"scope,omitempty"`        // права доступа через пробел
+	AuthTime     Time          `json:"auth_time,omitempty"`    // время аутентификации
+	ACR          string        `json:"acr,omitempty"`          // класс аутентификации
+	AMR          []string      `json:"amr,omitempty"`          // методы аутентификации
+	Groups       []string      `json:"groups,omitempty"`       // группы пользователя
+	Roles        []string      `json:"roles,omitempty"`        // роли пользователя
+	Entitlements []string      `json:"entitlements,omitempty"` // индивидуальные права
+	Confirmation *Confirmation `json:"cnf,omitempty"`          // ключ, с которым связан токен
+}
+
+// AccessTokenConfig описывает шаблон для выпуска токенов доступа в формате
+// RFC 9068. Токены формируются так же, как это делает Config, но в заголовке
+// указывается тип "at+jwt", а время создания (iat) и уникальный
+// идентификатор (jti) добавляются всегда. Если UniqueID не задан, то
+// используется Nonce(16).
+type AccessTokenConfig struct {
+	Config
+}
+
+// Token возвращает подписанный токен доступа. Если в нем не заданы все
+// обязательные поля (iss, exp, aud, sub, client_id, iat, jti), то
+// возвращается ошибка. Токены доступа без подписи не выпускаются.
+func (c AccessTokenConfig) Token(token AccessToken) (string, error) {
+	if c.Key == nil {
+		return "", ErrEmptySignKey
+	}
+
+	conf := c.Config
+	conf.Created = true
+	if conf.UniqueID == nil {
+		conf.UniqueID = Nonce(16)
+	}
+
+	claims, err := conf.claims(token)
+	if err != nil {
+		return "", err
+	}
+	// проверяем поля в том виде, в каком их получит сервер ресурсов
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	decoded := make(JSON)
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return "", err
+	}
+	if err := requireAccessTokenClaims(&Token{Claims: decoded}); err != nil {
+		return "", err
+	}
+
+	return conf.encode(claims, &jwsHeader{Type: AccessTokenType})
+}
+
+// RequireAccessToken проверяет, что токен является токеном доступа в
+// формате RFC 9068: в заголовке указан тип "at+jwt" и заданы все обязательные
+// поля. Пустые значения iss, sub, client_id, jti и aud считаются не
+// заданными. Проверку издателя и получателя нужно добавить отдельно с помощью
+// IssuedBy и HasAudience.
+func RequireAccessToken() Validator {
+	return func(token *Token) error {
+		verr := new(ValidationError)
+		if !typeMatch(token.Header.Type, AccessTokenType) {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: "typ", Err: ErrBadType})
+		}
+		verr.merge(requireAccessTokenClaims(token))
+		if len(verr.Errors) > 0 {
+			return verr
+		}
+		return nil
+	}
+}
+
+// requireAccessTokenClaims проверяет, что в токене доступа заданы все
+// обязательные поля и строковые поля среди них не пустые.
+func requireAccessTokenClaims(token *Token) error {
+	verr := new(ValidationError)
+	verr.merge(RequireClaims(accessTokenClaims...)(token))
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	for _, name := range []string{"iss", "sub", "client_id", "jti"} {
+		if token.String(name) == "" {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: name,
+				Err: fmt.Errorf("%w: empty value", ErrMissingClaim)})
+		}
+	}
+	audience := claimAudience(token.Claims["aud"])
+	if len(audience) == 0 || audience[0] == "" {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "aud",
+			Err: fmt.Errorf("%w: empty value", ErrMissingClaim)})
+	}
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// VerifyAccessToken проверяет токен доступа в соответствии с RFC 9068,
+// раздел 4, и возвращает его содержимое. Ключ для проверки подписи
+// обязателен. Издатель и получатель токена проверяются с помощью
+// validators:
+//
+//	claims, err := jwt.VerifyAccessToken(token, keys.Key,
+//		jwt.IssuedBy("https://as.example.com/"),
+//		jwt.HasAudience("https://rs.example.com/"))
+func VerifyAccessToken(token string, key interface{}, validators ...Validator) (*AccessToken, error) {
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+
+	parsed, err := parse(token, key, AccessTokenType,
+		append([]Validator{RequireAccessToken()}, validators...))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := new(AccessToken)
+	if err := json.Unmarshal(parsed.Raw, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessToken(t *testing.T) {
+	key := NewES256Key()
+	issuer := AccessTokenConfig{Config: Config{
+		Issuer:  "https://as.example.com/",
+		Expires: time.Hour,
+		Key:     key,
+	}}
+
+	token, err := issuer.Token(AccessToken{
+		Claims: Claims{
+			Subject:  "5ba552d67",
+			Audience: Audience{"https://rs.example.com/"},
+		},
+		ClientID: "s6BhdRkqt3",
+		Scope:    "openid profile reademail",
+		Groups:   []string{"admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if !strings.Contains(string(header), `"typ":"at+jwt"`) {
+		t.Errorf("bad header: %s", header)
+	}
+
+	claims, err := VerifyAccessToken(token, &key.PublicKey,
+		IssuedBy("https://as.example.com/"),
+		HasAudience("https://rs.example.com/"),
+		ClaimContains("scope", "reademail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.ClientID != "s6BhdRkqt3" || claims.ID == "" || claims.Created.IsZero() ||
+		len(claims.Groups) != 1 {
+		t.Errorf("bad claims: %+v", claims)
+	}
+
+	// обычный токен не является токеном доступа
+	if _, err := Verify(token, &key.PublicKey); !errors.Is(err, ErrBadType) {
+		t.Error("access token accepted as JWT:", err)
+	}
+	jwt, err := Config{Key: key, Created: true, Expires: time.Hour,
+		UniqueID: Nonce(8), Issuer: "https://as.example.com/"}.Token(JSON{
+		"sub": "5ba552d67", "aud": "https://rs.example.com/", "client_id": "s6BhdRkqt3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAccessToken(jwt, &key.PublicKey); !errors.Is(err, ErrBadType) {
+		t.Error("JWT accepted as access token:", err)
+	}
+
+	// неверный получатель
+	if _, err := VerifyAccessToken(token, &key.PublicKey,
+		HasAudience("https://other.example.com/")); !errors.Is(err, ErrBadClaim) {
+		t.Error("bad audience accepted:", err)
+	}
+
+	// обязательные поля
+	if _, err := issuer.Token(AccessToken{Claims: Claims{Subject: "5ba552d67"}}); !errors.Is(err, ErrMissingClaim) {
+		t.Error("access token without aud and client_id:", err)
+	}
+	if _, err := issuer.Token(AccessToken{Claims: Claims{Subject: "5ba552d67",
+		Audience: Audience{"https://rs.example.com/"}}}); !errors.Is(err, ErrMissingClaim) {
+		t.Error("access token with empty client_id:", err)
+	}
+	now := time.Now().Unix()
+	empty, err := encode(JSON{"iss": "", "sub": "", "client_id": "", "aud": "https://rs.example.com/",
+		"iat": now, "exp": now + 60, "jti": "1"}, key, &jwsHeader{Type: AccessTokenType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyAccessToken(empty, &key.PublicKey)
+	if !errors.Is(err, ErrMissingClaim) {
+		t.Fatal("access token with empty claims accepted:", err)
+	}
+	rejected := make(map[string]bool)
+	for _, err := range err.(*ValidationError).Errors {
+		if cerr, ok := err.(*ClaimError); ok {
+			rejected[cerr.Claim] = true
+		}
+	}
+	if !rejected["iss"] || !rejected["sub"] || !rejected["client_id"] || rejected["aud"] {
+		t.Errorf("bad rejected claims: %v", rejected)
+	}
+	if _, err := (AccessTokenConfig{}).Token(AccessToken{}); err != ErrEmptySignKey {
+		t.Error("unsigned access token:", err)
+	}
+}
+
+func TestAuthenticatorType(t *testing.T) {
+	if !typeMatch("application/AT+JWT", AccessTokenType) || typeMatch("", AccessTokenType) ||
+		!typeMatch("", "") || typeMatch("at+jwt", "") {
+		t.Error("bad type match")
+	}
+}
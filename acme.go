@@ -0,0 +1,307 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ACMEContentType задает тип содержимого запросов ACME (RFC 8555,
+// раздел 6.2).
+const ACMEContentType = "application/jose+json"
+
+// JWS описывает подписанное сообщение в формате Flattened JWS JSON
+// Serialization (RFC 7515, раздел 7.2.2).
+type JWS struct {
+	Protected string `json:"protected"` // защищенный заголовок в base64
+	Payload   string `json:"payload"`   // содержимое в base64
+	Signature string `json:"signature"` // подпись в base64
+}
+
+// ACMERequest описывает параметры подписи запроса к серверу ACME (RFC 8555,
+// раздел 6.2).
+//
+// Запрос подписывается ключом учетной записи Key (*rsa.PrivateKey или
+// *ecdsa.PrivateKey, в том числе возвращаемым функцией). Если задан KeyID
+// (адрес учетной записи), то он указывается в заголовке kid. Иначе в
+// заголовок jwk помещается открытый ключ: так подписываются запросы на
+// создание учетной записи и отзыв сертификата ключом сертификата.
+type ACMERequest struct {
+	URL   string      // url - адрес запроса
+	Nonce string      // nonce - значение из заголовка Replay-Nonce
+	KeyID string      // kid - адрес учетной записи
+	Key   interface{} // ключ учетной записи
+}
+
+// Sign возвращает подписанный запрос, содержимым которого является payload
+// в формате JSON. Если payload равен nil, то возвращается запрос POST-as-GET
+// с пустым содержимым.
+func (r ACMERequest) Sign(payload interface{}) (*JWS, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.URL == "" {
+		return nil, errors.New("url required")
+	}
+	_, key := signingKey(r.Key)
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+	jwk, err := signerJWK(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &jwsHeader{Nonce: r.Nonce, URL: r.URL}
+	signKey := interface{}(key)
+	if r.KeyID != "" {
+		signKey = func() (string, interface{}) { return r.KeyID, key }
+	} else {
+		header.JWK = jwk
+	}
+	token, err := encodePayload(data, signKey, header)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(token, ".")
+	return &JWS{Protected: parts[0], Payload: parts[1], Signature: parts[2]}, nil
+}
+
+// KeyChange возвращает запрос на смену ключа учетной записи (RFC 8555,
+// раздел 7.3.5) по адресу URL. Вложенное сообщение подписывается новым
+// ключом newKey и содержит адрес учетной записи и прежний открытый ключ, а
+// внешнее - текущим ключом Key с адресом учетной записи KeyID.
+func (r ACMERequest) KeyChange(newKey interface{}) (*JWS, error) {
+	if r.KeyID == "" {
+		return nil, errors.New("account url required")
+	}
+	_, key := signingKey(r.Key)
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+	oldKey, err := signerJWK(key)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := ACMERequest{URL: r.URL, Key: newKey}.Sign(JSON{
+		"account": r.KeyID,
+		"oldKey":  oldKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Key = key
+	return r.Sign(inner)
+}
+
+// ACMEMessage описывает проверенный запрос ACME.
+type ACMEMessage struct {
+	Header  Header // защищенный заголовок
+	Payload []byte // содержимое; пустое для запросов POST-as-GET
+	Key     *JWK   // ключ, которым подписан запрос
+}
+
+// PostAsGet возвращает true для запросов POST-as-GET с пустым содержимым.
+func (m *ACMEMessage) PostAsGet() bool {
+	return len(m.Payload) == 0
+}
+
+// ACMEVerifier проверяет подписанные запросы на стороне сервера ACME
+// (RFC 8555, раздел 6).
+//
+// Проверяется, что запрос представлен в формате Flattened JWS без
+// незащищенного заголовка, подписан одним из алгоритмов Algorithms (по
+// умолчанию RS256, ES256, ES384 и ES512), адрес url в заголовке совпадает с
+// адресом запроса, а ключ задан ровно одним из заголовков jwk и kid. Ключ
+// учетной записи по ее адресу из kid возвращает функция Account.
+//
+// Если задана функция Nonce, то она проверяет и погашает значение nonce из
+// заголовка. Она вызывается только для запросов с верной подписью.
+type ACMEVerifier struct {
+	Algorithms []string                              // допустимые алгоритмы подписи
+	Account    func(accountURL string) (*JWK, error) // ключ учетной записи
+	Nonce      func(nonce string) error              // проверка nonce
+}
+
+// Verify проверяет запрос body, полученный по адресу url. Какой из
+// заголовков jwk и kid допустим для данного адреса, проверяет вызывающий.
+func (v *ACMEVerifier) Verify(body []byte, url string) (*ACMEMessage, error) {
+	return v.verify(body, url, true)
+}
+
+// VerifyRequest проверяет HTTP-запрос ACME. Так как сервер может находиться
+// за прокси, полный адрес запроса url передается явно.
+func (v *ACMEVerifier) VerifyRequest(r *http.Request, url string) (*ACMEMessage, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		mediaType != ACMEContentType {
+		return nil, fmt.Errorf("content type %s required", ACMEContentType)
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxDocumentSize))
+	if err != nil {
+		return nil, err
+	}
+	return v.Verify(body, url)
+}
+
+// KeyChange проверяет вложенное сообщение запроса на смену ключа учетной
+// записи (RFC 8555, раздел 7.3.5) и возвращает новый ключ. Сам запрос msg
+// должен быть уже проверен с помощью Verify. Проверить, что новый ключ не
+// используется другой учетной записью, должен вызывающий.
+func (v *ACMEVerifier) KeyChange(msg *ACMEMessage) (*JWK, error) {
+	if msg.Header.KeyID == "" {
+		return nil, &ValidationError{Kind: ClaimsInvalid, Errors: []error{
+			&ClaimError{Claim: "kid", Err: ErrMissingClaim}}}
+	}
+	inner, err := v.verify(msg.Payload, msg.Header.URL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var request struct {
+		Account string `json:"account"`
+		OldKey  *JWK   `json:"oldKey"`
+	}
+	if err := json.Unmarshal(inner.Payload, &request); err != nil {
+		return nil, &ValidationError{Kind: Malformed, Errors: []error{
+			fmt.Errorf("%w: %v", ErrInvalid, err)}}
+	}
+	verr := new(ValidationError)
+	if request.Account != msg.Header.KeyID {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "account",
+			Err: fmt.Errorf("%w: %q", ErrBadClaim, request.Account)})
+	}
+	current, err := msg.Key.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	if request.OldKey == nil {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "oldKey", Err: ErrMissingClaim})
+	} else if old, err := request.OldKey.Thumbprint(); err != nil || old != current {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "oldKey", Err: ErrBadClaim})
+	}
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return inner.Key, nil
+}
+
+// verify проверяет запрос ACME. Для вложенного сообщения смены ключа (outer
+// равен false) заголовок nonce не допускается, а ключ должен быть задан в
+// заголовке jwk.
+func (v *ACMEVerifier) verify(body []byte, url string, outer bool) (*ACMEMessage, error) {
+	verr := new(ValidationError)
+	fail := func(kind ErrorKind, err error) (*ACMEMessage, error) {
+		verr.add(kind, err)
+		return nil, verr
+	}
+	malformed := func(err error) (*ACMEMessage, error) {
+		return fail(Malformed, fmt.Errorf("%w: %v", ErrInvalid, err))
+	}
+
+	// поле payload обязательно, даже если оно пустое (RFC 8555, раздел 6.3)
+	var jws struct {
+		JWS
+		Payload    *string         `json:"payload"`
+		Header     json.RawMessage `json:"header"`
+		Signatures json.RawMessage `json:"signatures"`
+	}
+	if err := json.Unmarshal(body, &jws); err != nil {
+		return malformed(err)
+	}
+	if jws.Header != nil || jws.Signatures != nil {
+		return malformed(errors.New("unprotected header or multiple signatures"))
+	}
+	if jws.Protected == "" || jws.Payload == nil || jws.Signature == "" {
+		return malformed(errors.New("protected header, payload and signature required"))
+	}
+
+	msg := new(ACMEMessage)
+	data, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return malformed(err)
+	}
+	if err := json.Unmarshal(data, &msg.Header); err != nil {
+		return malformed(err)
+	}
+	if msg.Payload, err = base64.RawURLEncoding.DecodeString(*jws.Payload); err != nil {
+		return malformed(err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil {
+		return malformed(err)
+	}
+
+	// проверяем заголовок
+	header := &msg.Header
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256", "ES256", "ES384", "ES512"}
+	}
+	verr.merge(AllowAlgorithms(algorithms...)(&Token{Header: *header}))
+	if header.URL != url {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "url",
+			Err: fmt.Errorf("%w: %q", ErrBadClaim, header.URL)})
+	}
+	switch {
+	case outer && header.Nonce == "":
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "nonce", Err: ErrMissingClaim})
+	case !outer && header.Nonce != "":
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "nonce",
+			Err: fmt.Errorf("%w: not allowed in inner JWS", ErrBadClaim)})
+	}
+	switch {
+	case (header.JWK == nil) == (header.KeyID == ""):
+		return fail(Malformed, fmt.Errorf("%w: exactly one of jwk and kid required", ErrInvalid))
+	case !outer && header.JWK == nil:
+		return fail(Malformed, fmt.Errorf("%w: jwk required in inner JWS", ErrInvalid))
+	}
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+
+	// проверяем подпись
+	msg.Key = header.JWK
+	if header.KeyID != "" {
+		if v.Account == nil {
+			return fail(SignatureInvalid, ErrEmptySignKey)
+		}
+		if msg.Key, err = v.Account(header.KeyID); err != nil {
+			return fail(SignatureInvalid, err)
+		}
+		if msg.Key == nil {
+			return fail(SignatureInvalid, ErrEmptySignKey)
+		}
+	}
+	public, err := msg.Key.publicKey()
+	if err != nil {
+		return fail(SignatureInvalid, err)
+	}
+	if name, _ := algorithm(public); name != header.Algorithm {
+		return fail(SignatureInvalid, fmt.Errorf("%w: algorithm %s does not match key",
+			ErrBadSignature, header.Algorithm))
+	}
+	if err := verify([]byte(jws.Protected+"."+*jws.Payload), signature, public); err != nil {
+		return fail(SignatureInvalid, err)
+	}
+
+	// nonce погашается только для запросов с верной подписью
+	if outer && v.Nonce != nil {
+		if err := v.Nonce(header.Nonce); err != nil {
+			return fail(ClaimsInvalid, &ClaimError{Claim: "nonce", Err: err})
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestACME(t *testing.T) {
+	const (
+		newAccountURL = "https://acme.example.com/acme/new-account"
+		orderURL      = "https://acme.example.com/acme/order/1"
+		keyChangeURL  = "https://acme.example.com/acme/key-change"
+		accountURL    = "https://acme.example.com/acme/acct/1"
+	)
+
+	accounts := make(map[string]*JWK)
+	nonces := map[string]bool{"n1": true, "n2": true, "n3": true, "n4": true}
+	verifier := &ACMEVerifier{
+		Account: func(kid string) (*JWK, error) {
+			if key, ok := accounts[kid]; ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("account %q does not exist", kid)
+		},
+		Nonce: func(nonce string) error {
+			if !nonces[nonce] {
+				return ErrReplayed
+			}
+			delete(nonces, nonce)
+			return nil
+		},
+	}
+	body := func(jws *JWS) []byte {
+		data, _ := json.Marshal(jws)
+		return data
+	}
+
+	// создание учетной записи с ключом в заголовке jwk
+	accountKey := NewES256Key()
+	jws, err := ACMERequest{URL: newAccountURL, Nonce: "n1", Key: accountKey}.Sign(JSON{
+		"termsOfServiceAgreed": true,
+		"contact":              []string{"mailto:cert-admin@example.org"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/acme/new-account", bytes.NewReader(body(jws)))
+	r.Header.Set("Content-Type", ACMEContentType)
+	msg, err := verifier.VerifyRequest(r, newAccountURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Key == nil || msg.Header.KeyID != "" || msg.PostAsGet() ||
+		!bytes.Contains(msg.Payload, []byte("termsOfServiceAgreed")) {
+		t.Fatalf("bad new account request: %+v", msg)
+	}
+	accounts[accountURL] = msg.Key
+
+	// повторное использование nonce
+	if _, err := verifier.Verify(body(jws), newAccountURL); !errors.Is(err, ErrReplayed) {
+		t.Error("nonce reused:", err)
+	}
+
+	// запрос POST-as-GET с адресом учетной записи в kid
+	request := ACMERequest{URL: orderURL, Nonce: "n2", KeyID: accountURL, Key: accountKey}
+	jws, err = request.Sign(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jws.Payload != "" {
+		t.Errorf("POST-as-GET payload: %q", jws.Payload)
+	}
+	msg, err = verifier.Verify(body(jws), orderURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.PostAsGet() || msg.Header.KeyID != accountURL || msg.Header.JWK != nil {
+		t.Errorf("bad POST-as-GET request: %+v", msg)
+	}
+
+	// запрос для другого адреса, без nonce и с чужой подписью
+	request.Nonce = "n3"
+	jws, _ = request.Sign(nil)
+	if _, err := verifier.Verify(body(jws), newAccountURL); !errors.Is(err, ErrBadClaim) {
+		t.Error("wrong url accepted:", err)
+	}
+	request.Nonce = ""
+	jws, _ = request.Sign(nil)
+	if _, err := verifier.Verify(body(jws), orderURL); !errors.Is(err, ErrMissingClaim) {
+		t.Error("request without nonce accepted:", err)
+	}
+	forged := request
+	forged.Nonce, forged.Key = "n3", NewES256Key()
+	jws, _ = forged.Sign(nil)
+	if _, err := verifier.Verify(body(jws), orderURL); !errors.Is(err, ErrBadSignature) {
+		t.Error("forged request accepted:", err)
+	}
+	if !nonces["n3"] {
+		t.Error("nonce consumed by forged request")
+	}
+	if _, err := verifier.Verify([]byte(`{"protected":"e30","payload":"","signature":"AA",`+
+		`"header":{"kid":"x"}}`), orderURL); !errors.Is(err, ErrInvalid) {
+		t.Error("unprotected header accepted:", err)
+	}
+	// POST-as-GET без поля payload
+	jws, _ = ACMERequest{URL: orderURL, Nonce: "n3", KeyID: accountURL, Key: accountKey}.Sign(nil)
+	noPayload, _ := json.Marshal(JSON{"protected": jws.Protected, "signature": jws.Signature})
+	var verr *ValidationError
+	if _, err := verifier.Verify(noPayload, orderURL); !errors.As(err, &verr) || verr.Kind != Malformed {
+		t.Error("request without payload accepted:", err)
+	}
+	noKey := ACMERequest{URL: keyChangeURL, KeyID: accountURL}
+	if _, err := noKey.KeyChange(NewES256Key()); err != ErrEmptySignKey {
+		t.Error("key change without key:", err)
+	}
+
+	// смена ключа учетной записи
+	newKey := NewRS256Key()
+	change := ACMERequest{URL: keyChangeURL, Nonce: "n3", KeyID: accountURL, Key: accountKey}
+	jws, err = change.KeyChange(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = verifier.Verify(body(jws), keyChangeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := verifier.KeyChange(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := JWKEncode(&newKey.PublicKey, "")
+	if key.N != want.N || key.E != want.E {
+		t.Error("bad new key")
+	}
+	accounts[accountURL] = key
+
+	// старый ключ больше не действует, новый - действует
+	jws, _ = ACMERequest{URL: orderURL, Nonce: "n4", KeyID: accountURL, Key: accountKey}.Sign(nil)
+	if _, err := verifier.Verify(body(jws), orderURL); !errors.Is(err, ErrBadSignature) {
+		t.Error("old key accepted:", err)
+	}
+	jws, _ = ACMERequest{URL: orderURL, Nonce: "n4", KeyID: accountURL, Key: newKey}.Sign(nil)
+	if _, err := verifier.Verify(body(jws), orderURL); err != nil {
+		t.Error(err)
+	}
+
+	// вложенное сообщение для другой учетной записи
+	inner, _ := ACMERequest{URL: keyChangeURL, Key: NewES256Key()}.Sign(JSON{
+		"account": "https://acme.example.com/acme/acct/2", "oldKey": key})
+	payload, _ := json.Marshal(inner)
+	if _, err := verifier.KeyChange(&ACMEMessage{
+		Header:  Header{KeyID: accountURL, URL: keyChangeURL},
+		Payload: payload,
+		Key:     key,
+	}); !errors.Is(err, ErrBadClaim) {
+		t.Error("key change for other account accepted:", err)
+	}
+}
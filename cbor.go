@@ -0,0 +1,385 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// cborTag описывает значение CBOR с тегом (RFC 8949, раздел 3.4).
+type cborTag struct {
+	Number  uint64      // номер тега
+	Content interface{} // значение с тегом
+}
+
+// cborMaxDepth ограничивает вложенность разбираемых значений CBOR.
+const cborMaxDepth = 32
+
+// cborMarshal возвращает представление значения в формате CBOR. Поддерживаются
+// nil, bool, int, int64, uint64, float64, string, []byte, []interface{},
+// map[string]interface{}, map[interface{}]interface{} и cborTag. Ключи
+// словарей сортируются в соответствии с детерминированным кодированием
+// (RFC 8949, раздел 4.2.1).
+func cborMarshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := cborEncode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cborHead записывает начальный байт элемента CBOR с основным типом major и
+// аргументом n в кратчайшей форме.
+func cborHead(buf *bytes.Buffer, major byte, n uint64) {
+	var b [8]byte
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.BigEndian.PutUint16(b[:], uint16(n))
+		buf.Write(b[:2])
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.BigEndian.PutUint32(b[:], uint32(n))
+		buf.Write(b[:4])
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.BigEndian.PutUint64(b[:], n)
+		buf.Write(b[:])
+	}
+}
+
+// cborEncode записывает значение в формате CBOR.
+func cborEncode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case int:
+		cborInt(buf, int64(v))
+	case int64:
+		cborInt(buf, v)
+	case uint64:
+		cborHead(buf, 0, v)
+	case float64:
+		// используем кратчайшее представление без потери точности
+		var b [8]byte
+		if f := float32(v); float64(f) == v || math.IsNaN(v) {
+			buf.WriteByte(0xfa)
+			binary.BigEndian.PutUint32(b[:], math.Float32bits(f))
+			buf.Write(b[:4])
+		} else {
+			buf.WriteByte(0xfb)
+			binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+			buf.Write(b[:])
+		}
+	case string:
+		cborHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []byte:
+		cborHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case []interface{}:
+		cborHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			if err := cborEncode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		return cborEncodeMap(buf, m)
+	case map[interface{}]interface{}:
+		return cborEncodeMap(buf, v)
+	case cborTag:
+		cborHead(buf, 6, v.Number)
+		return cborEncode(buf, v.Content)
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", v)
+	}
+	return nil
+}
+
+// cborInt записывает целое число со знаком.
+func cborInt(buf *bytes.Buffer, n int64) {
+	if n < 0 {
+		cborHead(buf, 1, uint64(-1-n))
+	} else {
+		cborHead(buf, 0, uint64(n))
+	}
+}
+
+// cborEncodeMap записывает словарь, упорядочивая элементы по байтовому
+// представлению ключей.
+func cborEncodeMap(buf *bytes.Buffer, m map[interface{}]interface{}) error {
+	type entry struct{ key, value []byte }
+	entries := make([]entry, 0, len(m))
+	for key, value := range m {
+		k, err := cborMarshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := cborMarshal(value)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{k, v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	cborHead(buf, 5, uint64(len(entries)))
+	for _, e := range entries {
+		buf.Write(e.key)
+		buf.Write(e.value)
+	}
+	return nil
+}
+
+// errCBORTruncated возвращается при неожиданном окончании данных CBOR.
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// cborUnmarshal разбирает единственное значение в формате CBOR. Целые числа
+// возвращаются как int64 (или uint64, если не помещаются в int64), числа с
+// плавающей точкой - как float64, словари - как map[interface{}]interface{},
+// а значения с тегом - как cborTag. Значения неопределенной длины не
+// поддерживаются.
+func cborUnmarshal(data []byte) (interface{}, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.data) > 0 {
+		return nil, errors.New("cbor: unexpected data after value")
+	}
+	return v, nil
+}
+
+// cborDecoder разбирает данные в формате CBOR.
+type cborDecoder struct {
+	data  []byte // оставшиеся данные
+	depth int    // текущая вложенность
+}
+
+// head разбирает начальный байт элемента и его аргумент.
+func (d *cborDecoder) head() (major, info byte, n uint64, err error) {
+	if len(d.data) == 0 {
+		return 0, 0, 0, errCBORTruncated
+	}
+	major, info = d.data[0]>>5, d.data[0]&0x1f
+	d.data = d.data[1:]
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(d.data) < size {
+			return 0, 0, 0, errCBORTruncated
+		}
+		for _, b := range d.data[:size] {
+			n = n<<8 | uint64(b)
+		}
+		d.data = d.data[size:]
+		return major, info, n, nil
+	case info == 31:
+		return 0, 0, 0, errors.New("cbor: indefinite length not supported")
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: reserved additional information %d", info)
+	}
+}
+
+// value разбирает очередное значение.
+func (d *cborDecoder) value() (interface{}, error) {
+	if d.depth++; d.depth > cborMaxDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	defer func() { d.depth-- }()
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0: // положительное целое
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+
+	case 1: // отрицательное целое
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(n), nil
+
+	case 2, 3: // байтовая и текстовая строки
+		if n > uint64(len(d.data)) {
+			return nil, errCBORTruncated
+		}
+		data := d.data[:n]
+		d.data = d.data[n:]
+		if major == 2 {
+			return append([]byte(nil), data...), nil
+		}
+		if !utf8.Valid(data) {
+			return nil, errors.New("cbor: invalid UTF-8 string")
+		}
+		return string(data), nil
+
+	case 4: // массив
+		if n > uint64(len(d.data)) {
+			return nil, errCBORTruncated
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+
+	case 5: // словарь
+		if n > uint64(len(d.data))/2 {
+			return nil, errCBORTruncated
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, uint64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, ok := m[key]; ok {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			if m[key], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case 6: // значение с тегом
+		content, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{Number: n, Content: content}, nil
+
+	default: // простые значения и числа с плавающей точкой
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return cborHalfFloat(uint16(n)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(n))), nil
+		case 27:
+			return math.Float64frombits(n), nil
+		default:
+			return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+		}
+	}
+}
+
+// cborHalfFloat преобразует число половинной точности (IEEE 754 binary16).
+func cborHalfFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(mant+1024, exp-25)
+}
+
+// cborJSON преобразует разобранное значение CBOR к виду, в котором
+// encoding/json возвращает разобранные данные: числа становятся float64, а
+// целочисленные ключи словарей - строками. Байтовые строки остаются как есть,
+// а теги отбрасываются. Словарь, в котором целочисленный и строковый ключи
+// дают одно и то же имя (например, 1 и "1"), считается ошибкой.
+func cborJSON(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = cborJSON(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[interface{}]interface{}:
+		m := make(JSON, len(v))
+		for key, value := range v {
+			name, err := cborKeyName(key)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := m[name]; ok {
+				return nil, fmt.Errorf("cbor: duplicate map key %q", name)
+			}
+			if m[name], err = cborJSON(value); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case cborTag:
+		return cborJSON(v.Content)
+	default:
+		return v, nil
+	}
+}
+
+// cborKeyName возвращает строковое представление ключа словаря.
+func cborKeyName(key interface{}) (string, error) {
+	switch key := key.(type) {
+	case string:
+		return key, nil
+	case int64:
+		return strconv.FormatInt(key, 10), nil
+	case uint64:
+		return strconv.FormatUint(key, 10), nil
+	default:
+		return "", fmt.Errorf("cbor: unsupported map key type %T", key)
+	}
+}
@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCBOR(t *testing.T) {
+	// RFC 8949, приложение A
+	for _, test := range []struct {
+		value interface{}
+		hex   string
+	}{
+		{int64(0), "00"},
+		{int64(23), "17"},
+		{int64(24), "1818"},
+		{int64(1000000), "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{int64(-1000), "3903e7"},
+		{1.5, "fa3fc00000"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{nil, "f6"},
+		{"ü", "62c3bc"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]interface{}{int64(1), []interface{}{int64(2), int64(3)}}, "8201820203"},
+		{map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}, "a201020304"},
+		{map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}, "a26161016162820203"},
+		{cborTag{Number: 1, Content: int64(1363896240)}, "c11a514b67b0"},
+	} {
+		data, err := cborMarshal(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(data) != test.hex {
+			t.Errorf("encode %v: %x, want %s", test.value, data, test.hex)
+		}
+		value, err := cborUnmarshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(value, test.value) {
+			t.Errorf("decode %s: %#v", test.hex, value)
+		}
+	}
+
+	// половинная точность
+	for hexValue, want := range map[string]float64{
+		"f93c00": 1,
+		"f97bff": 65504,
+		"f90001": 5.960464477539063e-8,
+		"f9c400": -4,
+		"f97c00": math.Inf(1),
+	} {
+		data, _ := hex.DecodeString(hexValue)
+		if value, err := cborUnmarshal(data); err != nil || value != want {
+			t.Errorf("decode %s: %v %v", hexValue, value, err)
+		}
+	}
+
+	// ключи словаря упорядочиваются по байтовому представлению
+	data, _ := cborMarshal(JSON{"aa": int64(1), "b": int64(2)})
+	if hex.EncodeToString(data) != "a261620262616101" {
+		t.Errorf("bad map order: %x", data)
+	}
+
+	for _, hexValue := range []string{
+		"",           // нет данных
+		"1a0000",     // неполное число
+		"62c3",       // неполная строка
+		"9f01ff",     // неопределенная длина
+		"a201020103", // повторяющийся ключ
+		"6180",       // неверная строка UTF-8
+		"0000",       // лишние данные
+		"9affffffff", // слишком длинный массив
+	} {
+		data, _ := hex.DecodeString(hexValue)
+		if _, err := cborUnmarshal(data); err == nil {
+			t.Errorf("decode %s: no error", hexValue)
+		}
+	}
+
+	// целочисленный и строковый ключи с одинаковым именем
+	if _, err := cborJSON(map[interface{}]interface{}{int64(1): "a", "1": "b"}); err == nil {
+		t.Error("duplicate key name accepted")
+	}
+}
@@ -0,0 +1,69 @@
+package jwt
+
+import "encoding/json"
+
+// Claims описывает зарегистрированные поля токена (RFC 7519, раздел 4.1).
+//
+// Структуру удобно встраивать в собственные структуры с описанием полей
+// токена: ее поля будут подняты на верхний уровень как при генерации токена
+// через Config.Token, так и при распаковке через json.Unmarshal или Decode.
+// При генерации токена через Config.Token не заданные значения в токен не
+// попадают. При кодировании через json.Marshal или Encode не заданное время
+// представляется как null (в Go 1.24 и новее такие поля опускаются).
+//
+//	type MyClaims struct {
+//		jwt.Claims
+//		Email string `json:"email"`
+//	}
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"` // идентификатор выпускающего
+	Subject   string   `json:"sub,omitempty"` // идентификатор субъекта
+	Audience  Audience `json:"aud,omitempty"` // получатели токена
+	Expires   Time     `json:"exp,omitzero"`  // время окончания действия
+	NotBefore Time     `json:"nbf,omitzero"`  // время начала действия
+	Created   Time     `json:"iat,omitzero"`  // время создания
+	ID        string   `json:"jti,omitempty"` // уникальный идентификатор
+}
+
+// Audience описывает список получателей токена. В формате JSON единственный
+// получатель представляется строкой, а несколько - массивом строк.
+type Audience []string
+
+// Contains возвращает true, если указанный получатель есть в списке.
+func (a Audience) Contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON представляет единственного получателя в виде строки.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON восстанавливает список получателей, представленный строкой
+// или массивом строк.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var aud string
+	if err := json.Unmarshal(data, &aud); err == nil {
+		if aud == "" {
+			*a = nil
+		} else {
+			*a = Audience{aud}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
@@ -0,0 +1,159 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokenPayload возвращает распакованное содержимое токена без проверки.
+func tokenPayload(t *testing.T, token string) JSON {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(JSON)
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+type Tenant struct {
+	Tenant string `json:"tenant_id"`
+	Name   string `json:"name"` // перекрывается полем верхнего уровня
+}
+
+type testClaims struct {
+	Claims
+	*Tenant
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+func TestConfigEmbeddedClaims(t *testing.T) {
+	conf := Config{
+		Issuer:  "http://service.example.com/",
+		Expires: time.Hour,
+		Key:     "secret",
+	}
+
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	token, err := conf.Token(&testClaims{
+		Claims: Claims{
+			Subject:  "9394203942934",
+			Audience: Audience{"api"},
+			Created:  Time{Time: created},
+		},
+		Tenant: &Tenant{Tenant: "acme", Name: "ignored"},
+		Name:   "Test User",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := tokenPayload(t, token)
+	for name, value := range map[string]interface{}{
+		"iss":       conf.Issuer,
+		"sub":       "9394203942934",
+		"aud":       "api",
+		"iat":       float64(created.Unix()),
+		"tenant_id": "acme",
+		"name":      "Test User",
+	} {
+		if payload[name] != value {
+			t.Errorf("bad %q: %v", name, payload[name])
+		}
+	}
+	for _, name := range []string{"Claims", "Tenant", "jti", "nbf", "email"} {
+		if _, ok := payload[name]; ok {
+			t.Errorf("unexpected %q", name)
+		}
+	}
+	if _, ok := payload["exp"]; !ok {
+		t.Error("exp from config overridden by empty claim")
+	}
+
+	var claimset testClaims
+	if err := Decode(token, &claimset); err != nil {
+		t.Fatal(err)
+	}
+	if claimset.Subject != "9394203942934" || !claimset.Audience.Contains("api") ||
+		!claimset.Created.Equal(created) || claimset.Expires.IsZero() {
+		t.Errorf("bad decoded claims: %+v", claimset.Claims)
+	}
+
+	// встроенная структура, заданная пустым указателем, игнорируется
+	token, err = conf.Token(testClaims{Name: "Test User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tokenPayload(t, token)["tenant_id"]; ok {
+		t.Error("nil embedded struct encoded")
+	}
+}
+
+func TestClaimFieldsConflict(t *testing.T) {
+	type A struct{ Name, Value string }
+	type B struct {
+		Name  string
+		Value string `json:"value"`
+	}
+	type C struct {
+		A
+		B
+	}
+
+	var names []string
+	for _, field := range claimFields(reflect.TypeOf(C{})) {
+		names = append(names, field.name)
+	}
+	if strings.Join(names, ",") != "value" {
+		t.Errorf("bad fields: %v", names)
+	}
+}
+
+func TestAudience(t *testing.T) {
+	for data, expected := range map[string]int{
+		`"api"`:         1,
+		`["api","web"]`: 2,
+		`null`:          0,
+	} {
+		var aud Audience
+		if err := json.Unmarshal([]byte(data), &aud); err != nil {
+			t.Fatal(err)
+		}
+		if len(aud) != expected {
+			t.Errorf("bad audience %s: %v", data, aud)
+		}
+	}
+	if data, _ := json.Marshal(Audience{"api"}); string(data) != `"api"` {
+		t.Errorf("bad single audience: %s", data)
+	}
+}
+
+func TestClaimsZeroTimes(t *testing.T) {
+	token, err := Encode(Claims{Subject: "user"}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := tokenPayload(t, token)
+	for _, name := range []string{"exp", "nbf", "iat"} {
+		if claims[name] != nil {
+			t.Errorf("zero %s encoded: %v", name, claims[name])
+		}
+	}
+	if _, err := Verify(token, "secret"); err != nil {
+		t.Error("token with zero times rejected:", err)
+	}
+
+	var decoded Claims
+	data, _ := json.Marshal(Claims{Subject: "user"})
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Expires.IsZero() {
+		t.Errorf("bad round trip: %s %v", data, err)
+	}
+}
@@ -0,0 +1,241 @@
+package jwt
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jwtTimeType       = reflect.TypeOf(Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// claimValue преобразует значение поля токена в вид, пригодный для
+// кодирования в JSON. Значения time.Time на любом уровне вложенности
+// представляются в числовом виде, а не заданные значения времени
+// пропускаются: в этом случае второе возвращаемое значение равно false.
+//
+// Типы, поддерживающие json.Marshaler или encoding.TextMarshaler, кодируются
+// с их помощью. Структуры и словари преобразуются в JSON по тем же правилам,
+// что и сам токен. Для каналов, функций и комплексных чисел возвращается
+// ошибка.
+func claimValue(v reflect.Value) (interface{}, bool, error) {
+	if !v.IsValid() {
+		return nil, true, nil
+	}
+
+	// время всегда представляется в числовом виде
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return nil, false, nil // игнорируем пустые даты
+		}
+		data, err := Time{Time: t}.MarshalJSON()
+		if err != nil {
+			return nil, false, err
+		}
+		return json.RawMessage(data), true, nil
+	}
+	if v.Type() == jwtTimeType && v.Interface().(Time).IsZero() {
+		return nil, false, nil // игнорируем пустые даты
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, true, nil
+		}
+	default:
+		// учитываем методы, определенные для указателя на тип
+		if v.CanAddr() && (reflect.PtrTo(v.Type()).Implements(jsonMarshalerType) ||
+			reflect.PtrTo(v.Type()).Implements(textMarshalerType)) {
+			v = v.Addr()
+		}
+	}
+
+	// используем собственные методы кодирования, если они определены
+	if v.Type().Implements(jsonMarshalerType) {
+		data, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, false, err
+		}
+		return json.RawMessage(data), true, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, false, err
+		}
+		return string(text), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return claimValue(v.Elem())
+
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr, reflect.Float32, reflect.Float64, reflect.String:
+		return v.Interface(), true, nil
+
+	case reflect.Struct:
+		result, err := claimStruct(v)
+		if err != nil {
+			return nil, false, err
+		}
+		return result, true, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		result := make(JSON, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := claimKey(iter.Key())
+			if err != nil {
+				return nil, false, err
+			}
+			value, ok, err := claimValue(iter.Value())
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				result[key] = value
+			}
+		}
+		return result, true, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return nil, true, nil
+			}
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return v.Bytes(), true, nil // кодируется в base64
+			}
+		}
+		result := make([]interface{}, v.Len())
+		for i := range result {
+			value, _, err := claimValue(v.Index(i))
+			if err != nil {
+				return nil, false, err
+			}
+			result[i] = value // пустые даты в массиве представлены null
+		}
+		return result, true, nil
+
+	default:
+		return nil, false, fmt.Errorf("unsupported claim type %s", v.Type())
+	}
+}
+
+// claimStruct преобразует структуру в словарь с полями токена.
+func claimStruct(v reflect.Value) (JSON, error) {
+	result := make(JSON)
+	// перебираем все поля структуры, включая поля встроенных структур
+	for _, field := range claimFields(v.Type()) {
+		value, ok := fieldByIndex(v, field.index)
+		if !ok {
+			continue // встроенная структура не задана
+		}
+		// пропускаем пустые значения, которые указано игнорировать
+		if field.omitEmpty && value.IsZero() {
+			continue
+		}
+
+		val, ok, err := claimValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("claim %q: %w", field.name, err)
+		}
+		if !ok {
+			continue
+		}
+		// опция string: значения простых типов представляются строкой
+		if field.quoted {
+			val = quoteClaim(value, val)
+		}
+		// добавляем значение поля в наш результирующий словарь
+		result[field.name] = val
+	}
+
+	return result, nil
+}
+
+// claimKey возвращает строковое представление ключа словаря.
+func claimKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshalerType) {
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported claim key type %s", key.Type())
+	}
+}
+
+// quoteClaim представляет значение простого типа в виде строки с JSON, как
+// это делает опция string в encoding/json. Остальные значения, включая типы
+// с собственными методами кодирования, возвращаются без изменений.
+func quoteClaim(v reflect.Value, value interface{}) interface{} {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return value
+		}
+		v = v.Elem()
+	}
+	if typ := reflect.PtrTo(v.Type()); typ.Implements(jsonMarshalerType) ||
+		typ.Implements(textMarshalerType) {
+		return value
+	}
+
+	switch v.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr, reflect.Float32, reflect.Float64, reflect.String:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		return string(data)
+	default:
+		return value
+	}
+}
+
+// mergeClaims добавляет в result значения из словаря с полями токена.
+func mergeClaims(result JSON, claims map[string]interface{}) error {
+	keys := make([]string, 0, len(claims))
+	for key := range claims {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // для предсказуемых сообщений об ошибках
+
+	for _, key := range keys {
+		value, ok, err := claimValue(reflect.ValueOf(claims[key]))
+		if err != nil {
+			return fmt.Errorf("claim %q: %w", key, err)
+		}
+		if ok {
+			result[key] = value
+		}
+	}
+	return nil
+}
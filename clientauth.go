@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientAssertionType задает значение параметра client_assertion_type для
+// аутентификации клиента с помощью JWT (RFC 7523, раздел 2.2).
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// ClientAssertion описывает параметры для формирования токена, которым
+// клиент OAuth 2.0 подтверждает свою подлинность при обращении к серверу
+// авторизации (RFC 7523, раздел 3, метод private_key_jwt из OpenID Connect
+// Core 1.0, раздел 9).
+type ClientAssertion struct {
+	ClientID string        // iss и sub - идентификатор клиента
+	Audience string        // aud - адрес token endpoint или издатель сервера
+	Expires  time.Duration // exp - время жизни, по умолчанию 1 минута
+	Key      interface{}   // ключ для подписи в формате Config.Key
+}
+
+// Token возвращает подписанный токен для аутентификации клиента. В качестве
+// издателя и субъекта указывается идентификатор клиента, а в каждый токен
+// добавляется время создания и уникальный идентификатор (jti).
+func (a ClientAssertion) Token() (string, error) {
+	if a.Key == nil {
+		return "", ErrEmptySignKey
+	}
+	if a.ClientID == "" || a.Audience == "" {
+		return "", errors.New("client_id and audience required")
+	}
+
+	expires := a.Expires
+	if expires <= 0 {
+		expires = time.Minute
+	}
+	return Config{
+		Issuer:   a.ClientID,
+		Created:  true,
+		Expires:  expires,
+		UniqueID: Nonce(16),
+		Key:      a.Key,
+	}.Token(JSON{"sub": a.ClientID, "aud": a.Audience})
+}
+
+// Form возвращает параметры запроса к серверу авторизации с токеном для
+// аутентификации клиента. К ним можно добавить остальные параметры запроса.
+func (a ClientAssertion) Form() (url.Values, error) {
+	token, err := a.Token()
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{
+		"client_assertion_type": {ClientAssertionType},
+		"client_assertion":      {token},
+	}, nil
+}
+
+// ClientAssertionVerifier проверяет токены аутентификации клиентов на
+// стороне сервера авторизации (RFC 7523, раздел 3).
+//
+// Ключи клиента возвращает функция Keys по его идентификатору: обычно это
+// зарегистрированный набор ключей клиента или загруженный по его jwks_uri.
+// Проверяется, что iss и sub совпадают с идентификатором клиента, среди
+// получателей (aud) есть одно из значений Audience, заданы exp и jti, а
+// время жизни токена не превышает MaxLifetime (по умолчанию 5 минут).
+// Токен должен быть подписан одним из алгоритмов Algorithms (по умолчанию
+// RS256, ES256, ES384 и ES512). Если задан Replay, то каждый токен
+// принимается только один раз.
+type ClientAssertionVerifier struct {
+	Audience    []string                             // допустимые получатели
+	Keys        func(clientID string) (*Keys, error) // ключи клиента
+	Algorithms  []string                             // допустимые алгоритмы подписи
+	MaxLifetime time.Duration                        // максимальное время жизни токена
+	Replay      ReplayStore                          // хранилище использованных идентификаторов
+}
+
+// Verify проверяет токен аутентификации клиента и возвращает идентификатор
+// клиента.
+func (v *ClientAssertionVerifier) Verify(assertion string) (clientID string, err error) {
+	if v.Keys == nil {
+		return "", ErrEmptySignKey
+	}
+
+	// идентификатор клиента нужен для получения его ключей, поэтому сначала
+	// разбираем токен без проверки подписи
+	unverified, err := parse(assertion, nil, "", nil)
+	if err != nil {
+		return "", err
+	}
+	clientID = unverified.String("iss")
+	if clientID == "" {
+		return "", &ValidationError{Kind: ClaimsInvalid,
+			Errors: []error{&ClaimError{Claim: "iss", Err: ErrMissingClaim}}}
+	}
+	keys, err := v.Keys(clientID)
+	if err != nil {
+		return "", err
+	}
+
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256", "ES256", "ES384", "ES512"}
+	}
+	maxLifetime := v.MaxLifetime
+	if maxLifetime <= 0 {
+		maxLifetime = 5 * time.Minute
+	}
+
+	validator := All(
+		AllowAlgorithms(algorithms...),
+		RequireClaims("iss", "sub", "aud", "exp", "jti"),
+		ClaimEquals("sub", clientID),
+		CheckClaim("aud", func(value interface{}) error {
+			for _, aud := range claimAudience(value) {
+				for _, audience := range v.Audience {
+					if aud == audience {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("%w: %v", ErrBadClaim, value)
+		}),
+		func(token *Token) error {
+			expires, now := token.Time("exp"), time.Now()
+			if expires.After(now.Add(maxLifetime)) {
+				return &ClaimError{Claim: "exp", Time: expires, Now: now,
+					Err: fmt.Errorf("%w: lifetime too long", ErrBadClaim)}
+			}
+			return nil
+		},
+	)
+	validators := []Validator{validator}
+	if v.Replay != nil {
+		validators = append(validators, PreventReplay(v.Replay))
+	}
+
+	if _, err := parse(assertion, keys.Key, "", validators); err != nil {
+		return "", err
+	}
+	return clientID, nil
+}
+
+// VerifyRequest проверяет токен аутентификации клиента из параметров
+// запроса к серверу авторизации и возвращает идентификатор клиента. Если в
+// запросе указан client_id, то он должен совпадать с издателем токена.
+func (v *ClientAssertionVerifier) VerifyRequest(r *http.Request) (clientID string, err error) {
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+	if r.PostForm.Get("client_assertion_type") != ClientAssertionType {
+		return "", errors.New("unsupported client_assertion_type")
+	}
+	assertions := r.PostForm["client_assertion"]
+	if len(assertions) != 1 {
+		return "", errors.New("single client_assertion required")
+	}
+
+	clientID, err = v.Verify(assertions[0])
+	if err != nil {
+		return "", err
+	}
+	if id := r.PostForm.Get("client_id"); id != "" && id != clientID {
+		return "", fmt.Errorf("client_id %q does not match assertion", id)
+	}
+	return clientID, nil
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientAssertion(t *testing.T) {
+	key := NewES256Key()
+	jwk, _ := JWKEncode(&key.PublicKey, "client-key")
+	clients := map[string]*Keys{"s6BhdRkqt3": {Keys: []*JWK{jwk}}}
+
+	verifier := &ClientAssertionVerifier{
+		Audience: []string{"https://as.example.com/token"},
+		Keys: func(clientID string) (*Keys, error) {
+			keys, ok := clients[clientID]
+			if !ok {
+				return nil, fmt.Errorf("unknown client %q", clientID)
+			}
+			return keys, nil
+		},
+		Replay: NewReplayCache(10, 0),
+	}
+
+	assertion := ClientAssertion{
+		ClientID: "s6BhdRkqt3",
+		Audience: "https://as.example.com/token",
+		Key:      func() (string, interface{}) { return "client-key", key },
+	}
+	form, err := assertion.Form()
+	if err != nil {
+		t.Fatal(err)
+	}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", "s6BhdRkqt3")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := verifier.VerifyRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, clientID)
+	}))
+	defer server.Close()
+
+	for i, status := range []int{http.StatusOK, http.StatusUnauthorized} {
+		resp, err := server.Client().Post(server.URL, "application/x-www-form-urlencoded",
+			strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status { // второй запрос - повторное использование
+			t.Errorf("request %d: unexpected status %d", i, resp.StatusCode)
+		}
+	}
+
+	// неверные токены
+	wrongAudience := assertion
+	wrongAudience.Audience = "https://other.example.com/token"
+	longLived := assertion
+	longLived.Expires = time.Hour
+	unknown := assertion
+	unknown.ClientID = "unknown"
+	for name, assertion := range map[string]ClientAssertion{
+		"aud": wrongAudience,
+		"exp": longLived,
+	} {
+		token, err := assertion.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verifier.Verify(token); !errors.Is(err, ErrBadClaim) {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+	token, _ := unknown.Token()
+	if _, err := verifier.Verify(token); err == nil {
+		t.Error("unknown client accepted")
+	}
+
+	// подпись чужим ключом
+	forged := assertion
+	forged.Key = NewES256Key()
+	token, _ = forged.Token()
+	if _, err := verifier.Verify(token); !errors.Is(err, ErrBadSignature) {
+		t.Error("forged assertion accepted:", err)
+	}
+
+	// симметричные ключи не принимаются
+	hmac := assertion
+	hmac.Key = "secret"
+	clients["hmac"] = &Keys{Keys: []*JWK{{Type: "oct", K: "c2VjcmV0"}}}
+	hmac.ClientID = "hmac"
+	token, _ = hmac.Token()
+	if _, err := verifier.Verify(token); err == nil {
+		t.Error("HS256 assertion accepted")
+	}
+}
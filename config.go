@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -27,6 +28,11 @@ type JSON = map[string]interface{}
 // Чтобы указать в заголовке токена идентификатор ключа, используемого для
 // подписи, нужно чтобы функция ключа возвращала два значения: первым будет
 // KeyID, а вторым - сам ключ для подписи.
+//
+// Если задано Encryption, то подписанный токен дополнительно шифруется для
+// получателя и возвращается вложенный токен (Nested JWT) в формате JWE с
+// типом содержимого "JWT". Проверить такой токен можно с помощью
+// VerifyNested.
 type Config struct {
 	Issuer    string        // iss - идентификатор выпускающего
 	Created   bool          // iat - добавлять время создания
@@ -36,7 +42,8 @@ type Config struct {
 	UniqueID  func() string // nonce - генератор случайной строки
 	Private   JSON          // дополнительные именованные поля
 
-	Key interface{} // ключ для подписи токена или функция его возвращающая
+	Key        interface{} // ключ для подписи токена или функция его возвращающая
+	Encryption *Encryption // параметры шифрования подписанного токена
 }
 
 // Token возвращает сгенерированный токен на основании шаблона и
@@ -46,26 +53,39 @@ type Config struct {
 //
 // Есть несколько отличий от стандартной сериализации в формат JSON, которые
 // заложены в этой функции. Во-первых, все поля, представленные в виде
-// time.Time кодируются в числовом виде, а не строками, как это стандартно
-// происходит в Go. А не заданные значение time.Time автоматически игнорируются.
+// time.Time, кодируются в числовом виде, а не строками, как это стандартно
+// происходит в Go, на любом уровне вложенности. А не заданные значение
+// time.Time автоматически игнорируются.
 // Во-вторых, в структурах для именования полей могут использоваться теги "json"
 // и "jwt". Последний имеет чуть больший приоритет, поэтому вы можете специально
 // для токенов указывать другие имена. Если имя поля не определено в теге, то
 // используется само имя поля, но первая его буква при этом становится строчной,
-// что больше соответствует формату JSON токена.
+// что больше соответствует формату JSON токена. В-третьих, поля встроенных
+// (анонимных) структур поднимаются на верхний уровень по тем же правилам, что
+// и в encoding/json, что позволяет встраивать Claims в собственные структуры.
+//
+// Во всем остальном кодирование следует правилам encoding/json: типы,
+// поддерживающие json.Marshaler или encoding.TextMarshaler, кодируются с их
+// помощью, а опция тега "string" представляет простые значения строкой. Для
+// значений, которые не могут быть представлены в JSON (каналы, функции,
+// комплексные числа), возвращается ошибка.
 func (c Config) Token(claimset interface{}) (string, error) {
+	claims, err := c.claims(claimset)
+	if err != nil {
+		return "", err
+	}
+	return c.encode(claims, &jwsHeader{Type: "JWT"})
+}
+
+// claims возвращает содержимое токена, сформированное на основании шаблона
+// и предоставленных данных.
+func (c Config) claims(claimset interface{}) (JSON, error) {
 	// формируем содержимое токена
 	result := make(JSON)
 
 	// добавляем дополнительные поля из шаблона
-	for key, value := range c.Private {
-		if vt, ok := value.(time.Time); ok {
-			if vt.IsZero() {
-				continue // игнорируем пустые даты
-			}
-			value = vt.Unix() // преобразуем в числовой вид
-		}
-		result[key] = value
+	if err := mergeClaims(result, c.Private); err != nil {
+		return nil, err
 	}
 
 	// генерируем поля на основе данных шаблона
@@ -105,14 +125,20 @@ func (c Config) Token(claimset interface{}) (string, error) {
 		}
 
 	case JSON: // словарь в формате JSON
-		for key, value := range claimset {
-			// отдельно обрабатываем поля с датой и временем
-			if vt, ok := value.(time.Time); ok {
-				if vt.IsZero() {
-					continue // игнорируем пустые даты
-				}
-				value = vt.Unix() // преобразуем в числовой вид
-			}
+		if err := mergeClaims(result, claimset); err != nil {
+			return nil, err
+		}
+
+	case json.Marshaler: // собственный формат представления в JSON
+		data, err := claimset.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		claims := make(JSON)
+		if err := json.Unmarshal(data, &claims); err != nil {
+			return nil, fmt.Errorf("unsupported claimset type %T: %w", claimset, err)
+		}
+		for key, value := range claims {
 			result[key] = value
 		}
 
@@ -124,14 +150,68 @@ func (c Config) Token(claimset interface{}) (string, error) {
 
 		// проверяем, что данный тип данных мы поддерживаем
 		if k := v.Kind(); k == reflect.Invalid || k != reflect.Struct {
-			return "", fmt.Errorf("unsupported claimset type %T", claimset)
+			return nil, fmt.Errorf("unsupported claimset type %T", claimset)
 		}
 
-		typ := v.Type()
-		// перебираем все поля структуры
-		for i := 0; i < v.NumField(); i++ {
+		// перебираем все поля структуры, включая поля встроенных структур
+		claims, err := claimStruct(v)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range claims {
+			result[key] = value
+		}
+	}
+
+	return result, nil
+}
+
+// encode подписывает токен с указанным заголовком и, если задано
+// Encryption, шифрует его.
+func (c Config) encode(claims JSON, header *jwsHeader) (string, error) {
+	token, err := encode(claims, c.Key, header)
+	if err != nil || c.Encryption == nil {
+		return token, err
+	}
+
+	// шифруем подписанный токен
+	encryption := *c.Encryption
+	encryption.ContentType = "JWT"
+	return encryption.Encrypt([]byte(token))
+}
+
+// claimField описывает поле структуры, которое попадает в токен.
+type claimField struct {
+	name      string // имя элемента токена
+	index     []int  // путь к полю с учетом встроенных структур
+	tagged    bool   // имя задано в теге
+	omitEmpty bool   // пропускать пустые значения
+	quoted    bool   // представлять значение строкой
+}
+
+// claimFields возвращает список полей структуры, которые попадают в токен.
+// Поля встроенных (анонимных) структур, для которых не задано имя в теге,
+// поднимаются на уровень выше по тем же правилам, что и в encoding/json:
+// при совпадении имен побеждает менее вложенное поле, а при равной
+// вложенности - поле с именем из тега. Если выбрать поле не удается, то
+// все поля с таким именем игнорируются.
+func claimFields(typ reflect.Type) []claimField {
+	var fields []claimField
+	var walk func(typ reflect.Type, index []int, visited map[reflect.Type]bool)
+	walk = func(typ reflect.Type, index []int, visited map[reflect.Type]bool) {
+		if visited[typ] {
+			return // защищаемся от рекурсивного встраивания
+		}
+		visited[typ] = true
+		defer delete(visited, typ)
+
+		for i := 0; i < typ.NumField(); i++ {
 			field := typ.Field(i)
-			if field.PkgPath != "" {
+			ft := field.Type
+			if field.Anonymous && ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if field.PkgPath != "" && !(field.Anonymous && ft.Kind() == reflect.Struct) {
 				continue // игнорируем приватные поля
 			}
 
@@ -147,36 +227,125 @@ func (c Config) Token(claimset interface{}) (string, error) {
 				continue // явно указано игнорирование
 			}
 
-			value := v.Field(i)
-			// пропускаем пустые значения, которые указано игнорировать
+			var options string
 			if idx := strings.IndexByte(tag, ','); idx >= 0 {
-				if strings.Contains(tag[idx+1:], "omitempty") && value.IsZero() {
-					continue
-				}
-				tag = tag[:idx]
+				tag, options = tag[:idx], tag[idx+1:]
+			}
+
+			fieldIndex := make([]int, len(index)+1)
+			copy(fieldIndex, index)
+			fieldIndex[len(index)] = i
+
+			// поля встроенной структуры без имени поднимаем на уровень выше
+			if tag == "" && field.Anonymous && ft.Kind() == reflect.Struct {
+				walk(ft, fieldIndex, visited)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue // приватная встроенная структура с именем
 			}
 
+			cf := claimField{
+				name:   tag,
+				index:  fieldIndex,
+				tagged: tag != "",
+			}
+			for _, option := range strings.Split(options, ",") {
+				switch option {
+				case "omitempty":
+					cf.omitEmpty = true
+				case "string":
+					cf.quoted = true
+				}
+			}
 			// если имя не определено в теге элемента, то берем имя поля
-			if tag == "" {
+			if cf.name == "" {
 				// первую букву в имени приводим к нижнему регистру
 				runes := []rune(field.Name)
 				runes[0] = unicode.ToLower(runes[0])
-				tag = string(runes)
+				cf.name = string(runes)
 			}
+			fields = append(fields, cf)
+		}
+	}
+	walk(typ, nil, make(map[reflect.Type]bool))
 
-			val := value.Interface()
-			// подмена данных для времени
-			if vt, ok := val.(time.Time); ok {
-				if vt.IsZero() {
-					continue // игнорируем пустые даты
-				}
-				val = vt.Unix() // подменяем на число
+	// разрешаем конфликты имен
+	byName := make(map[string][]claimField, len(fields))
+	for _, field := range fields {
+		byName[field.name] = append(byName[field.name], field)
+	}
+	result := make([]claimField, 0, len(fields))
+	for _, field := range fields {
+		dominant, ok := dominantField(byName[field.name])
+		if ok && sameIndex(dominant.index, field.index) {
+			result = append(result, field)
+		}
+	}
+
+	return result
+}
+
+// dominantField выбирает из полей с одинаковым именем то, которое попадет в
+// токен.
+func dominantField(fields []claimField) (claimField, bool) {
+	// оставляем только наименее вложенные поля
+	depth := len(fields[0].index)
+	for _, field := range fields[1:] {
+		if len(field.index) < depth {
+			depth = len(field.index)
+		}
+	}
+	candidates := make([]claimField, 0, len(fields))
+	for _, field := range fields {
+		if len(field.index) == depth {
+			candidates = append(candidates, field)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	// из нескольких полей выбираем единственное с именем из тега
+	var dominant *claimField
+	for i := range candidates {
+		if candidates[i].tagged {
+			if dominant != nil {
+				return claimField{}, false
 			}
-			// добавляем значение поля в наш результирующий словарь
-			result[tag] = val
+			dominant = &candidates[i]
+		}
+	}
+	if dominant == nil {
+		return claimField{}, false
+	}
+	return *dominant, true
+}
+
+// sameIndex возвращает true, если пути к полям совпадают.
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
+	return true
+}
 
-	// кодируем и возвращаем токен
-	return Encode(result, c.Key)
+// fieldByIndex возвращает значение поля по его пути. Если одна из встроенных
+// структур задана указателем, равным nil, то возвращается false.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
 }
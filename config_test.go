@@ -2,8 +2,11 @@ package jwt
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfig(t *testing.T) {
@@ -39,3 +42,111 @@ func TestConfigFuncKey(t *testing.T) {
 
 	fmt.Println("token:", token)
 }
+
+func TestConfigNested(t *testing.T) {
+	signKey := NewES256Key()
+	encryptKey := NewES256Key()
+
+	conf := Config{
+		Issuer:     "http://service.example.com/",
+		Expires:    time.Hour,
+		Key:        signKey,
+		Encryption: &Encryption{Key: &encryptKey.PublicKey},
+	}
+
+	token, err := conf.Token(JSON{"sub": "9394203942934"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(token, "."); n != 4 {
+		t.Fatal("token is not encrypted")
+	}
+
+	claim, err := VerifyNested(token, encryptKey, signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claimset struct {
+		Issuer  string `json:"iss"`
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(claim, &claimset); err != nil {
+		t.Fatal(err)
+	}
+	if claimset.Subject != "9394203942934" || claimset.Issuer != conf.Issuer {
+		t.Errorf("bad claimset: %+v", claimset)
+	}
+
+	if _, err := VerifyNested(token, encryptKey, NewES256Key()); err == nil {
+		t.Error("verified with wrong sign key")
+	}
+
+	// зашифрованное содержимое, не являющееся токеном
+	plain, err := conf.Encryption.Encrypt([]byte(`{"sub":"1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyNested(plain, encryptKey, signKey); err != ErrBadType {
+		t.Error("bad content type accepted:", err)
+	}
+}
+
+type testLevel int
+
+func (l testLevel) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("level-%d", int(l))), nil
+}
+
+type testPoint struct{ X, Y int }
+
+func (p *testPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{p.X, p.Y})
+}
+
+func TestConfigClaimEncoding(t *testing.T) {
+	updated := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	type profile struct {
+		Updated  time.Time            `json:"updated"`
+		Deleted  time.Time            `json:"deleted"`
+		History  []time.Time          `json:"history"`
+		Events   map[string]time.Time `json:"events"`
+		Level    testLevel            `json:"level"`
+		Location testPoint            `json:"location"`
+	}
+	claimset := &struct {
+		Profile profile `json:"profile"`
+		Count   int     `json:"count,string"`
+		Flag    *bool   `json:"flag,string,omitempty"`
+	}{
+		Profile: profile{
+			Updated:  updated,
+			History:  []time.Time{updated, {}},
+			Events:   map[string]time.Time{"login": updated, "logout": {}},
+			Level:    3,
+			Location: testPoint{X: 1, Y: 2},
+		},
+		Count: 42,
+	}
+
+	token, err := Config{}.Token(claimset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(tokenPayload(t, token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `{"count":"42","profile":{"events":{"login":1614600000},` +
+		`"history":[1614600000,null],"level":"level-3","location":[1,2],` +
+		`"updated":1614600000}}`
+	if string(data) != expected {
+		t.Errorf("bad claimset:\n%s\n%s", data, expected)
+	}
+
+	if _, err := (Config{}).Token(JSON{"callback": func() {}}); err == nil {
+		t.Error("unsupported claim type encoded")
+	}
+	if _, err := (Config{}).Token(struct{ C chan int }{}); err == nil {
+		t.Error("unsupported claim type encoded")
+	}
+}
@@ -0,0 +1,328 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Теги CBOR для CWT и сообщений COSE (RFC 8392, RFC 9052).
+const (
+	cwtTag       = 61 // CBOR Web Token
+	coseSign1Tag = 18 // COSE_Sign1
+	coseMac0Tag  = 17 // COSE_Mac0
+)
+
+// Метки полей заголовка COSE.
+const (
+	coseHeaderAlg = 1 // алгоритм
+	coseHeaderKID = 4 // идентификатор ключа
+)
+
+// coseAlgorithms задает идентификаторы алгоритмов COSE (RFC 9053, RFC 8812).
+var coseAlgorithms = map[string]int64{
+	"ES256": -7,
+	"ES384": -35,
+	"ES512": -36,
+	"RS256": -257,
+	"HS256": 5, // HMAC 256/256
+}
+
+// cwtClaimKeys задает целочисленные ключи зарегистрированных полей CWT
+// (RFC 8392, раздел 3). Поле jti представляется в CWT как cti с байтовым
+// значением.
+var cwtClaimKeys = map[string]int64{
+	"iss": 1,
+	"sub": 2,
+	"aud": 3,
+	"exp": 4,
+	"nbf": 5,
+	"iat": 6,
+	"jti": 7,
+}
+
+// CWT возвращает токен в формате CBOR Web Token (RFC 8392), сформированный
+// по тем же правилам, что и Token. Поддерживаются только алгоритмы ES256,
+// ES384, ES512, RS256 и HMAC 256/256; EdDSA и шифрование для CWT не
+// поддерживаются.
+//
+// Зарегистрированные поля iss, sub, aud, exp, nbf, iat и jti представляются
+// целочисленными ключами, а остальные поля - строковыми. Токен
+// подписывается в формате COSE_Sign1 ключами ECDSA или RSA, а для ключей
+// HMAC используется COSE_Mac0. Идентификатор ключа, если он задан,
+// помещается в незащищенный заголовок.
+func (c Config) CWT(claimset interface{}) ([]byte, error) {
+	if c.Encryption != nil {
+		return nil, errors.New("encryption not supported for CWT")
+	}
+	claims, err := c.claims(claimset)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := cwtClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, key := signingKey(c.Key)
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+	name, hash := algorithm(key)
+	alg, ok := coseAlgorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if !hash.Available() {
+		return nil, ErrBadHashFunc
+	}
+
+	protected, err := cborMarshal(map[interface{}]interface{}{int64(coseHeaderAlg): alg})
+	if err != nil {
+		return nil, err
+	}
+	unprotected := make(map[interface{}]interface{})
+	if keyID != "" {
+		unprotected[int64(coseHeaderKID)] = []byte(keyID)
+	}
+
+	tag := uint64(coseSign1Tag)
+	if name == "HS256" {
+		tag = coseMac0Tag
+	}
+	signature, err := sign(coseToBeSigned(tag, protected, payload), key)
+	if err != nil {
+		return nil, err
+	}
+	return cborMarshal(cborTag{Number: tag,
+		Content: []interface{}{protected, unprotected, payload, signature}})
+}
+
+// cwtClaims возвращает содержимое токена в формате CBOR. Значения
+// предварительно приводятся к виду JSON, чтобы к ним применялись те же
+// правила представления, что и для JWT.
+func cwtClaims(claims JSON) ([]byte, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var normalized JSON
+	if err := dec.Decode(&normalized); err != nil {
+		return nil, err
+	}
+
+	result := make(map[interface{}]interface{}, len(normalized))
+	for name, value := range normalized {
+		value = cwtValue(value)
+		key, ok := cwtClaimKeys[name]
+		if !ok {
+			result[name] = value
+			continue
+		}
+		switch name {
+		case "exp", "nbf", "iat":
+			switch value.(type) {
+			case int64, float64:
+			default:
+				return nil, &ClaimError{Claim: name, Err: fmt.Errorf("%w: not a number", ErrBadClaim)}
+			}
+		case "jti":
+			s, ok := value.(string)
+			if !ok {
+				return nil, &ClaimError{Claim: name, Err: fmt.Errorf("%w: not a string", ErrBadClaim)}
+			}
+			value = []byte(s)
+		}
+		result[key] = value
+	}
+	return cborMarshal(result)
+}
+
+// cwtValue заменяет числа json.Number на int64 или float64.
+func cwtValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = cwtValue(item)
+		}
+	case JSON:
+		for key, value := range v {
+			v[key] = cwtValue(value)
+		}
+	}
+	return v
+}
+
+// coseToBeSigned возвращает данные для подписи сообщения COSE_Sign1 или
+// COSE_Mac0 без дополнительных внешних данных (RFC 9052, разделы 4.4 и 6.3).
+func coseToBeSigned(tag uint64, protected, payload []byte) []byte {
+	context := "Signature1"
+	if tag == coseMac0Tag {
+		context = "MAC0"
+	}
+	data, _ := cborMarshal([]interface{}{context, protected, []byte{}, payload})
+	return data
+}
+
+// VerifyCWT проверяет подпись токена в формате CWT так же, как Verify, и
+// возвращает его в разобранном виде. Поддерживаются только алгоритмы ES256,
+// ES384, ES512, RS256 и HMAC 256/256; токены EdDSA не принимаются.
+// Принимаются сообщения COSE_Sign1 и COSE_Mac0 с тегом и, необязательно, с
+// внешним тегом CWT. Ключ задается в тех же форматах, что и для Verify; в
+// функцию ключа передаются алгоритм и идентификатор ключа из заголовка COSE.
+//
+// Зарегистрированные поля CWT возвращаются под именами JWT (iss, sub, aud,
+// exp, nbf, iat и jti), а числа - в виде float64, поэтому для CWT
+// подходят те же проверки Validator, что и для JWT. Значение cti, не
+// являющееся строкой UTF-8, возвращается в jti в кодировке base64url. Токен,
+// в котором два ключа дают одно имя (например, 1 и "iss"), считается неверно
+// сформированным. Raw содержит неразобранное содержимое токена в формате
+// CBOR.
+//
+// Как и для Verify, проверяется, что токен актуален на данный момент.
+func VerifyCWT(token []byte, key interface{}, validators ...Validator) (*Token, error) {
+	verr := new(ValidationError)
+	malformed := func(err error) (*Token, error) {
+		if err != ErrInvalid {
+			err = fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		verr.add(Malformed, err)
+		return nil, verr
+	}
+
+	msg, err := cborUnmarshal(token)
+	if err != nil {
+		return malformed(err)
+	}
+	tagged, ok := msg.(cborTag)
+	if ok && tagged.Number == cwtTag {
+		tagged, ok = tagged.Content.(cborTag)
+	}
+	if !ok || (tagged.Number != coseSign1Tag && tagged.Number != coseMac0Tag) {
+		return malformed(errors.New("not a COSE_Sign1 or COSE_Mac0 message"))
+	}
+	parts, ok := tagged.Content.([]interface{})
+	if !ok || len(parts) != 4 {
+		return malformed(ErrInvalid)
+	}
+	protected, ok1 := parts[0].([]byte)
+	unprotected, ok2 := parts[1].(map[interface{}]interface{})
+	payload, ok3 := parts[2].([]byte)
+	signature, ok4 := parts[3].([]byte)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return malformed(ErrInvalid)
+	}
+
+	// разбираем заголовок
+	parsed := new(Token)
+	header := &parsed.Header
+	params := make(map[interface{}]interface{})
+	if len(protected) > 0 {
+		v, err := cborUnmarshal(protected)
+		if err != nil {
+			return malformed(err)
+		}
+		if params, ok = v.(map[interface{}]interface{}); !ok {
+			return malformed(errors.New("protected header is not a map"))
+		}
+	}
+	alg, _ := params[int64(coseHeaderAlg)].(int64)
+	for name, id := range coseAlgorithms {
+		if id == alg {
+			header.Algorithm = name
+		}
+	}
+	if header.Algorithm == "" {
+		return malformed(fmt.Errorf("unsupported algorithm %d", alg))
+	}
+	if (header.Algorithm == "HS256") != (tagged.Number == coseMac0Tag) {
+		return malformed(fmt.Errorf("algorithm %s not allowed for COSE tag %d",
+			header.Algorithm, tagged.Number))
+	}
+	kid, ok := params[int64(coseHeaderKID)].([]byte)
+	if !ok {
+		kid, _ = unprotected[int64(coseHeaderKID)].([]byte)
+	}
+	header.KeyID = string(kid)
+
+	// разбираем содержимое
+	v, err := cborUnmarshal(payload)
+	if err != nil {
+		return malformed(err)
+	}
+	claims, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return malformed(errors.New("claims is not a map"))
+	}
+	parsed.Raw = payload
+	parsed.Claims = make(JSON, len(claims))
+	for key, value := range claims {
+		name, err := cborKeyName(key)
+		if err != nil {
+			return malformed(err)
+		}
+		for claim, id := range cwtClaimKeys {
+			if key == id {
+				name = claim
+			}
+		}
+		if _, ok := parsed.Claims[name]; ok {
+			return malformed(fmt.Errorf("duplicate claim %q", name))
+		}
+		if id, ok := value.([]byte); ok && name == "jti" {
+			if utf8.Valid(id) {
+				value = string(id)
+			} else {
+				value = base64.RawURLEncoding.EncodeToString(id)
+			}
+		}
+		if parsed.Claims[name], err = cborJSON(value); err != nil {
+			return malformed(err)
+		}
+	}
+	for _, name := range []string{"iat", "exp", "nbf"} {
+		if value, ok := parsed.Claims[name]; ok {
+			if _, ok := value.(float64); !ok {
+				return malformed(fmt.Errorf("%s is not a number", name))
+			}
+		}
+	}
+
+	// проверяем поля со временем
+	checkTimes(verr, parsed.Time("iat"), parsed.Time("exp"), parsed.Time("nbf"))
+
+	// проверяем подпись токена
+	if key, err := verifyKey(header, key); err == nil {
+		if name, _ := algorithm(key); name != header.Algorithm {
+			verr.add(SignatureInvalid, fmt.Errorf("%w: algorithm %s does not match key",
+				ErrBadSignature, header.Algorithm))
+		} else if err := verify(coseToBeSigned(tagged.Number, protected, payload),
+			signature, key); err != nil {
+			verr.add(SignatureInvalid, err)
+		}
+	} else if err != errSkipSignature {
+		verr.add(SignatureInvalid, err)
+	}
+
+	// дополнительные проверки выполняются только для токенов с верной
+	// подписью
+	if !verr.Has(SignatureInvalid) {
+		parsed.validate(verr, validators)
+	}
+
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return parsed, nil
+}
@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCWT(t *testing.T) {
+	ecKey := NewES256Key()
+	for name, key := range map[string]interface{}{
+		"ES256": ecKey,
+		"RS256": NewRS256Key(),
+		"HS256": "secret",
+		"kid":   func() (string, interface{}) { return "device-1", ecKey },
+	} {
+		conf := Config{
+			Issuer:   "coap://as.example.com",
+			Created:  true,
+			Expires:  time.Hour,
+			UniqueID: Nonce(8),
+			Private:  JSON{"aud": "coap://light.example.com"},
+			Key:      key,
+		}
+		token, err := conf.CWT(JSON{"sub": "erikw", "scope": []string{"read", "write"}, "level": 3})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		verifyKey := key
+		if fkey, ok := key.(func() (string, interface{})); ok {
+			verifyKey = func(alg, kid string) interface{} {
+				if kid != "device-1" || alg != "ES256" {
+					return nil
+				}
+				_, key := fkey()
+				return key
+			}
+		}
+		parsed, err := VerifyCWT(token, verifyKey,
+			IssuedBy("coap://as.example.com"), HasAudience("coap://light.example.com"),
+			RequireClaims("jti"))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		scope, _ := parsed.Claims["scope"].([]interface{})
+		if parsed.String("sub") != "erikw" || len(scope) != 2 || parsed.Claims["level"] != 3.0 ||
+			parsed.Time("exp").Before(time.Now()) || parsed.Header.Algorithm != name && name != "kid" {
+			t.Errorf("%s: bad claims: %v %+v", name, parsed.Claims, parsed.Header)
+		}
+
+		// CWT компактнее аналогичного JWT
+		jwt, _ := conf.Token(JSON{"sub": "erikw", "scope": []string{"read", "write"}, "level": 3})
+		if len(token) >= len(jwt) {
+			t.Errorf("%s: CWT is not smaller than JWT: %d >= %d", name, len(token), len(jwt))
+		}
+	}
+
+	// чужой ключ и измененное содержимое
+	token, _ := Config{Key: ecKey}.CWT("erikw")
+	if _, err := VerifyCWT(token, &NewES256Key().PublicKey); !errors.Is(err, ErrBadSignature) {
+		t.Error("forged CWT accepted:", err)
+	}
+	if _, err := VerifyCWT(token, "secret"); !errors.Is(err, ErrBadSignature) {
+		t.Error("CWT accepted with HMAC key:", err)
+	}
+	tampered := append([]byte(nil), token...)
+	tampered[len(tampered)-70] ^= 1
+	if _, err := VerifyCWT(tampered, &ecKey.PublicKey); err == nil {
+		t.Error("tampered CWT accepted")
+	}
+
+	// проверка времени
+	expired, _ := Config{Key: "secret"}.CWT(JSON{"exp": time.Now().Add(-time.Minute).Unix()})
+	if _, err := VerifyCWT(expired, "secret"); !errors.Is(err, ErrExpired) {
+		t.Error("expired CWT accepted:", err)
+	}
+	if _, err := VerifyCWT([]byte{0xa0}, "secret"); !errors.Is(err, ErrInvalid) {
+		t.Error("untagged message accepted:", err)
+	}
+
+	// одно поле задано и целочисленным, и строковым ключом
+	_, key := signingKey("secret")
+	protected, _ := cborMarshal(map[interface{}]interface{}{int64(coseHeaderAlg): coseAlgorithms["HS256"]})
+	mac := func(claims map[interface{}]interface{}) []byte {
+		payload, _ := cborMarshal(claims)
+		signature, err := sign(coseToBeSigned(coseMac0Tag, protected, payload), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _ := cborMarshal(cborTag{Number: coseMac0Tag, Content: []interface{}{
+			protected, map[interface{}]interface{}{}, payload, signature}})
+		return token
+	}
+	for _, claims := range []map[interface{}]interface{}{
+		{int64(1): "coap://as.example.com", "iss": "coap://evil.example.com"},
+		{int64(8): "a", "8": "b"},
+	} {
+		var verr *ValidationError
+		if _, err := VerifyCWT(mac(claims), "secret"); !errors.As(err, &verr) || verr.Kind != Malformed {
+			t.Errorf("duplicate claim accepted: %v %v", claims, err)
+		}
+	}
+
+	// cti, не являющийся строкой UTF-8
+	parsed, err := VerifyCWT(mac(map[interface{}]interface{}{int64(7): []byte{0x0b, 0x71, 0xff}}), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jti := parsed.String("jti"); jti != "C3H_" {
+		t.Errorf("bad jti: %q", jti)
+	}
+}
+
+func TestCWTExample(t *testing.T) {
+	// RFC 8392, приложение A.3
+	x, _ := new(big.Int).SetString("143329cce7868e416927599cf65a34f3ce2ffda55a7eca69ed8919a394d42f0f", 16)
+	y, _ := new(big.Int).SetString("60f7f1a780d8a783bfb7a2dd6b2796e8128dbbcef9d3d168db9529971a36e7b9", 16)
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	token, _ := hex.DecodeString("d28443a10126a104524173796d6d657472696345434453413235365850a70175636f" +
+		"61703a2f2f61732e6578616d706c652e636f6d02656572696b77037818636f61703a2f2f6c696768742e" +
+		"6578616d706c652e636f6d041a5612aeb0051a5610d9f0061a5610d9f007420b7158405427c1ff28d2" +
+		"3fbad1f29c4c7c6a555e601d6fa29f9179bc3d7438bacaca5acd08c8d4d4f96131680c429a01f8595" +
+		"1ecee743a52b9b63632c57209120e1c9e30")
+
+	var kid string
+	_, err := VerifyCWT(token, func(h *Header) interface{} {
+		kid = h.KeyID
+		return key
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Kind != ClaimsInvalid || !errors.Is(err, ErrExpired) {
+		t.Fatal("unexpected error:", err)
+	}
+	if kid != "AsymmetricECDSA256" {
+		t.Errorf("bad key id: %q", kid)
+	}
+}
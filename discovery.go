@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProviderMetadata описывает параметры провайдера OpenID Connect (OpenID
+// Connect Discovery 1.0, раздел 3) или сервера авторизации OAuth 2.0
+// (RFC 8414, раздел 2). Здесь перечислены только основные поля.
+type ProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                     string   `json:"token_endpoint,omitempty"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint,omitempty"`
+	JWKSURI                           string   `json:"jwks_uri,omitempty"`
+	RegistrationEndpoint              string   `json:"registration_endpoint,omitempty"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	SubjectTypesSupported             []string `json:"subject_types_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	ClaimsSupported                   []string `json:"claims_supported,omitempty"`
+}
+
+// Discover загружает описание провайдера с указанным идентификатором
+// издателя. Сначала запрашивается документ OpenID Connect
+// "/.well-known/openid-configuration", а если он не найден, то описание
+// сервера авторизации "/.well-known/oauth-authorization-server" в формате
+// RFC 8414. Издатель в полученном описании должен в точности совпадать с
+// запрошенным, иначе возвращается ошибка ErrIssuerMismatch.
+//
+// Если client не задан, то используется http.DefaultClient.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*ProviderMetadata, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("bad issuer identifier %q", issuer)
+	}
+
+	// OpenID Connect Discovery добавляет путь в конец идентификатора, а
+	// RFC 8414 вставляет его между адресом сервера и путем издателя
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	locations := []string{
+		strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration",
+		u.Scheme + "://" + u.Host + "/.well-known/oauth-authorization-server" + path,
+	}
+
+	var metadata *ProviderMetadata
+	for _, location := range locations {
+		metadata = new(ProviderMetadata)
+		err = fetchJSON(ctx, client, location, metadata)
+		if !errors.Is(err, errNotFound) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("%w: %q", ErrIssuerMismatch, metadata.Issuer)
+	}
+	return metadata, nil
+}
+
+// Keys загружает набор публичных ключей провайдера по адресу jwks_uri.
+func (m *ProviderMetadata) Keys(ctx context.Context, client *http.Client) (*Keys, error) {
+	if m.JWKSURI == "" {
+		return nil, errors.New("jwks_uri not specified")
+	}
+	keys := new(Keys)
+	if err := fetchJSON(ctx, client, m.JWKSURI, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Verifier загружает ключи провайдера и возвращает настроенную проверку
+// ID-токенов для указанного клиента. Допустимые алгоритмы подписи
+// берутся из id_token_signing_alg_values_supported, за исключением "none".
+func (m *ProviderMetadata) Verifier(ctx context.Context, client *http.Client, clientID string) (*IDTokenVerifier, error) {
+	keys, err := m.Keys(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithms := make([]string, 0, len(m.IDTokenSigningAlgValuesSupported))
+	for _, alg := range m.IDTokenSigningAlgValuesSupported {
+		if alg != "none" {
+			algorithms = append(algorithms, alg)
+		}
+	}
+
+	return &IDTokenVerifier{
+		Issuer:     m.Issuer,
+		ClientID:   clientID,
+		Key:        keys.Key,
+		Algorithms: algorithms,
+	}, nil
+}
+
+// errNotFound возвращается, если документ по указанному адресу не найден.
+var errNotFound = errors.New("not found")
+
+// maxDocumentSize ограничивает размер загружаемых документов.
+const maxDocumentSize = 1 << 20
+
+// fetchJSON загружает документ в формате JSON и разбирает его в v.
+func fetchJSON(ctx context.Context, client *http.Client, location string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s: %w", location, errNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s: unexpected status %s", location, resp.Status)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", location, err)
+	}
+	return nil
+}
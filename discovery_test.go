@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	key := NewES256Key()
+	jwk, err := JWKEncode(&key.PublicKey, "es1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProviderMetadata{
+			Issuer:                           issuer,
+			JWKSURI:                          issuer + "/jwks",
+			IDTokenSigningAlgValuesSupported: []string{"ES256", "none"},
+		})
+	})
+	// RFC 8414: путь издателя добавляется после well-known
+	mux.HandleFunc("/.well-known/oauth-authorization-server/tenant", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProviderMetadata{
+			Issuer:  issuer + "/tenant",
+			JWKSURI: issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/other", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProviderMetadata{Issuer: "https://evil.example.com"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Keys{Keys: []*JWK{jwk}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	issuer = server.URL
+	ctx, client := context.Background(), server.Client()
+
+	metadata, err := Discover(ctx, client, issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := metadata.Verifier(ctx, client, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verifier.Algorithms) != 1 || verifier.Algorithms[0] != "ES256" {
+		t.Errorf("bad algorithms: %v", verifier.Algorithms)
+	}
+
+	token, err := Config{
+		Issuer:  issuer,
+		Created: true,
+		Expires: time.Minute,
+		Key:     func() (string, interface{}) { return "es1", key },
+	}.Token(JSON{"sub": "user", "aud": "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(token, ""); err != nil {
+		t.Error(err)
+	}
+
+	// описание сервера авторизации в формате RFC 8414
+	if metadata, err := Discover(ctx, client, issuer+"/tenant"); err != nil {
+		t.Error(err)
+	} else if metadata.JWKSURI != issuer+"/jwks" {
+		t.Errorf("bad metadata: %+v", metadata)
+	}
+
+	if _, err := Discover(ctx, client, issuer+"/other"); !errors.Is(err, ErrIssuerMismatch) {
+		t.Error("issuer mismatch not detected:", err)
+	}
+	if _, err := Discover(ctx, client, issuer+"/unknown"); err == nil {
+		t.Error("unknown issuer discovered")
+	}
+}
@@ -1,7 +1,8 @@
 // Package jwt предоставляет возможности для удобного создания и проверки
 // токенов в формате JWT.
 //
-// Поддерживаются алгоритмы HS256, RS256 и ES256.
+// Поддерживаются алгоритмы HS256, RS256 и ES256. Для шифрования токенов в
+// формате JWE поддерживается согласование ключей ECDH-ES.
 //
 // Делалось исключительно для себя и подход принципиально отличается от
 // большинства существующих библиотек для работы с JWT: в первую очередь я
@@ -0,0 +1,229 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DPoPType задает тип доказательства DPoP в заголовке (RFC 9449, раздел 4.2).
+const DPoPType = "dpop+jwt"
+
+// DPoPProof описывает доказательство владения ключом (DPoP proof) для
+// HTTP-запроса в соответствии с RFC 9449.
+type DPoPProof struct {
+	Method      string // htm - метод HTTP-запроса
+	URL         string // htu - адрес HTTP-запроса
+	AccessToken string // токен доступа для вычисления ath
+	Nonce       string // nonce, полученный от сервера
+}
+
+// Sign возвращает доказательство, подписанное закрытым ключом клиента.
+// Поддерживаются ключи *rsa.PrivateKey и *ecdsa.PrivateKey или функция,
+// возвращающая такой ключ. Открытый ключ указывается в заголовке "jwk".
+//
+// Адрес запроса указывается без параметров и фрагмента. В каждое
+// доказательство добавляется время создания (iat) и уникальный
+// идентификатор (jti).
+func (p DPoPProof) Sign(key interface{}) (string, error) {
+	_, key = signingKey(key)
+	jwk, err := signerJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	htu, err := dpopURL(p.URL)
+	if err != nil {
+		return "", err
+	}
+	claims := JSON{
+		"jti": Nonce(16)(),
+		"htm": p.Method,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+	}
+	if p.AccessToken != "" {
+		claims["ath"] = sha256Base64(p.AccessToken)
+	}
+	if p.Nonce != "" {
+		claims["nonce"] = p.Nonce
+	}
+
+	return encode(claims, key, &jwsHeader{Type: DPoPType, JWK: jwk})
+}
+
+// DPoPVerifier проверяет доказательства владения ключом (DPoP proof) в
+// соответствии с RFC 9449, раздел 4.3.
+//
+// Проверяется, что доказательство подписано открытым ключом из заголовка
+// одним из алгоритмов Algorithms (по умолчанию RS256, ES256, ES384 и
+// ES512), соответствует методу и адресу запроса и создано не раньше MaxAge
+// (по умолчанию 5 минут). Если задан Replay, то каждое доказательство
+// принимается только один раз. Если задан Nonce, то доказательство должно
+// содержать именно это значение.
+type DPoPVerifier struct {
+	Algorithms []string      // допустимые алгоритмы подписи
+	MaxAge     time.Duration // максимальное время с момента создания
+	Replay     ReplayStore   // хранилище использованных идентификаторов
+	Nonce      string        // ожидаемое значение nonce
+}
+
+// Verify проверяет доказательство для запроса с указанным методом и адресом.
+// Если вместе с доказательством передается токен доступа, то он
+// указывается в accessToken, и проверяется поле ath.
+//
+// Возвращается отпечаток открытого ключа (jkt) по RFC 7638, который
+// сравнивается с полем cnf токена доступа с помощью BoundToKey.
+func (v *DPoPVerifier) Verify(proof, method, uri, accessToken string) (jkt string, err error) {
+	htu, err := dpopURL(uri)
+	if err != nil {
+		return "", err
+	}
+
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256", "ES256", "ES384", "ES512"}
+	}
+	maxAge := v.MaxAge
+	if maxAge <= 0 {
+		maxAge = 5 * time.Minute
+	}
+
+	// ключ для проверки подписи берется из заголовка
+	key := func(header *Header) interface{} {
+		if header.JWK == nil {
+			return fmt.Errorf("%w: jwk header", ErrInvalid)
+		}
+		public, err := header.JWK.publicKey()
+		if err != nil {
+			return err
+		}
+		if jkt, err = header.JWK.Thumbprint(); err != nil {
+			return err
+		}
+		return public
+	}
+
+	validators := []Validator{
+		AllowAlgorithms(algorithms...),
+		RequireClaims("jti", "htm", "htu", "iat"),
+		ClaimEquals("htm", method),
+		CheckClaim("htu", func(value interface{}) error {
+			s, _ := value.(string)
+			if u, err := dpopURL(s); err != nil || u != htu {
+				return fmt.Errorf("%w: %v", ErrBadClaim, value)
+			}
+			return nil
+		}),
+		func(token *Token) error {
+			created, now := token.Time("iat"), time.Now()
+			if !created.IsZero() && created.Add(maxAge).Before(now) {
+				return &ClaimError{Claim: "iat", Time: created, Now: now, Err: ErrExpired}
+			}
+			return nil
+		},
+	}
+	if accessToken != "" {
+		ath := sha256Base64(accessToken)
+		validators = append(validators, CheckClaim("ath", func(value interface{}) error {
+			s, _ := value.(string)
+			if subtle.ConstantTimeCompare([]byte(s), []byte(ath)) != 1 {
+				return ErrBadClaim
+			}
+			return nil
+		}))
+	}
+	if v.Nonce != "" {
+		validators = append(validators, ClaimEquals("nonce", v.Nonce))
+	}
+
+	if v.Replay != nil {
+		// доказательство не содержит exp, поэтому идентификатор хранится
+		// до тех пор, пока доказательство может быть принято
+		validators = append(validators, preventReplay(v.Replay, func(token *Token) time.Time {
+			return token.Time("iat").Add(maxAge)
+		}))
+	}
+
+	if _, err := parse(proof, key, DPoPType, validators); err != nil {
+		return "", err
+	}
+	return jkt, nil
+}
+
+// VerifyRequest извлекает доказательство из заголовка DPoP HTTP-запроса и
+// проверяет его так же, как Verify. Адрес запроса восстанавливается по
+// r.Host и r.URL.Path, а схема определяется по наличию TLS. Если сервер
+// работает за прокси, то адрес лучше восстановить самостоятельно и
+// воспользоваться Verify.
+func (v *DPoPVerifier) VerifyRequest(r *http.Request, accessToken string) (jkt string, err error) {
+	proofs := r.Header.Values("DPoP")
+	switch {
+	case len(proofs) == 0:
+		return "", errNoDPoPProof
+	case len(proofs) > 1:
+		return "", errMultipleDPoPProofs
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return v.Verify(proofs[0], r.Method, scheme+"://"+r.Host+r.URL.EscapedPath(), accessToken)
+}
+
+// Ошибки извлечения доказательства DPoP из запроса.
+var (
+	errNoDPoPProof        = errors.New("no DPoP proof in request")
+	errMultipleDPoPProofs = errors.New("multiple DPoP proofs in request")
+)
+
+// Confirmation описывает поле cnf токена, которое связывает его с ключом
+// клиента (RFC 7800 и RFC 9449, раздел 6).
+type Confirmation struct {
+	JKT string `json:"jkt,omitempty"` // отпечаток ключа DPoP
+}
+
+// BoundToKey проверяет, что токен связан с ключом, отпечаток которого
+// указан в jkt: поле cnf токена должно содержать это значение в jkt.
+func BoundToKey(jkt string) Validator {
+	return CheckClaim("cnf", func(value interface{}) error {
+		cnf, _ := value.(JSON)
+		s, _ := cnf["jkt"].(string)
+		if s == "" || subtle.ConstantTimeCompare([]byte(s), []byte(jkt)) != 1 {
+			return fmt.Errorf("%w: jkt mismatch", ErrBadClaim)
+		}
+		return nil
+	})
+}
+
+// sha256Base64 возвращает хеш SHA-256 строки в кодировке base64url. Так
+// вычисляются поле ath для токена доступа и дайджесты раскрытий SD-JWT.
+func sha256Base64(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// dpopURL приводит адрес запроса к виду, используемому в поле htu: без
+// параметров и фрагмента, со схемой и адресом сервера в нижнем регистре.
+func dpopURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("bad DPoP URL %q", s)
+	}
+	u.Scheme, u.Host = strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	u.RawQuery, u.Fragment, u.RawFragment = "", "", ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}
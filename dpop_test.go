@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	// RFC 7638, раздел 3.1
+	jwk := &JWK{
+		Type:      "RSA",
+		E:         "AQAB",
+		N:         "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		Algorithm: "RS256",
+		ID:        "2011-04-29",
+	}
+	thumbprint, err := jwk.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("bad thumbprint: %s", thumbprint)
+	}
+
+	// отпечаток закрытого ключа совпадает с отпечатком открытого
+	key := NewES256Key()
+	private, _ := JWKEncode(key, "")
+	public, _ := JWKEncode(&key.PublicKey, "")
+	t1, _ := private.Thumbprint()
+	t2, _ := public.Thumbprint()
+	if t1 != t2 || t1 == "" {
+		t.Errorf("thumbprint mismatch: %s != %s", t1, t2)
+	}
+}
+
+func TestDPoP(t *testing.T) {
+	key := NewES256Key()
+	jwk, _ := JWKEncode(&key.PublicKey, "")
+	thumbprint, _ := jwk.Thumbprint()
+
+	// токен доступа, связанный с ключом клиента
+	accessToken, err := AccessTokenConfig{Config: Config{
+		Issuer:  "https://as.example.com/",
+		Expires: time.Hour,
+		Key:     NewHS256Key(32),
+	}}.Token(AccessToken{
+		Claims:       Claims{Subject: "user", Audience: Audience{"https://rs.example.com/"}},
+		ClientID:     "client",
+		Confirmation: &Confirmation{JKT: thumbprint},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := DPoPProof{
+		Method:      "GET",
+		URL:         "https://rs.example.com/resource?x=1#f",
+		AccessToken: accessToken,
+	}.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := &DPoPVerifier{Replay: NewReplayCache(10, 0)}
+	jkt, err := verifier.Verify(proof, "GET", "https://RS.example.com/resource", accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jkt != thumbprint {
+		t.Errorf("bad jkt: %s", jkt)
+	}
+	if err := BoundToKey(jkt)(&Token{Claims: JSON{"cnf": JSON{"jkt": jkt}}}); err != nil {
+		t.Error(err)
+	}
+	if err := BoundToKey(jkt)(&Token{Claims: JSON{"cnf": JSON{"jkt": "other"}}}); !errors.Is(err, ErrBadClaim) {
+		t.Error("bad jkt accepted:", err)
+	}
+
+	// повторное использование
+	if _, err := verifier.Verify(proof, "GET", "https://rs.example.com/resource", accessToken); !errors.Is(err, ErrReplayed) {
+		t.Error("replayed proof accepted:", err)
+	}
+
+	for name, check := range map[string]struct {
+		method, uri, accessToken string
+	}{
+		"htm": {"POST", "https://rs.example.com/resource", accessToken},
+		"htu": {"GET", "https://rs.example.com/other", accessToken},
+		"ath": {"GET", "https://rs.example.com/resource", "other"},
+	} {
+		verifier := new(DPoPVerifier)
+		if _, err := verifier.Verify(proof, check.method, check.uri, check.accessToken); !errors.Is(err, ErrBadClaim) {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	// доказательство не является обычным токеном
+	if _, err := Verify(proof, &key.PublicKey); !errors.Is(err, ErrBadType) {
+		t.Error("DPoP proof accepted as JWT:", err)
+	}
+	// обычный токен не является доказательством
+	token, _ := Encode(JSON{"htm": "GET"}, key)
+	if _, err := new(DPoPVerifier).Verify(token, "GET", "https://rs.example.com/", ""); !errors.Is(err, ErrBadType) {
+		t.Error("JWT accepted as DPoP proof:", err)
+	}
+}
+
+func TestDPoPRequest(t *testing.T) {
+	key := NewRS256Key()
+	verifier := &DPoPVerifier{Nonce: "server-nonce"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := verifier.VerifyRequest(r, ""); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	for nonce, status := range map[string]int{
+		"server-nonce": http.StatusOK,
+		"other":        http.StatusUnauthorized,
+	} {
+		proof, err := DPoPProof{Method: "POST", URL: server.URL + "/token", Nonce: nonce}.Sign(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("POST", server.URL+"/token", nil)
+		req.Header.Set("DPoP", proof)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("nonce %s: unexpected status %d", nonce, resp.StatusCode)
+		}
+	}
+}
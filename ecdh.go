@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ecdhKeySize возвращает размер ключа, формируемого при согласовании по
+// ECDH-ES для указанного алгоритма. Для прямого согласования (ECDH-ES)
+// размер ключа определяется алгоритмом шифрования содержимого.
+func ecdhKeySize(alg, enc string) (int, error) {
+	switch alg {
+	case "ECDH-ES":
+		return cekSize(enc)
+	case "ECDH-ES+A128KW":
+		return 16, nil
+	case "ECDH-ES+A192KW":
+		return 24, nil
+	case "ECDH-ES+A256KW":
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported key management algorithm %q", alg)
+	}
+}
+
+// ecdhEncrypt генерирует эфемерный ключ, согласует с его помощью ключ с
+// публичным ключом получателя и возвращает ключ шифрования содержимого и его
+// зашифрованное представление. Эфемерный публичный ключ сохраняется в
+// заголовке.
+func ecdhEncrypt(header *jweHeader, pub *ecdsa.PublicKey, apu, apv []byte) (cek, encryptedKey []byte, err error) {
+	size, err := ecdhKeySize(header.Algorithm, header.Encryption)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ephemeral, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	epk, err := JWKEncode(&ephemeral.PublicKey, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	epk.Usage, epk.Algorithm = "", "" // в epk допустимы только параметры ключа
+	header.EphemeralKey = epk
+
+	algID := header.Algorithm
+	if algID == "ECDH-ES" {
+		algID = header.Encryption
+	}
+	key, err := ecdhDeriveKey(ephemeral, pub, algID, apu, apv, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// при прямом согласовании полученный ключ и является ключом шифрования
+	if header.Algorithm == "ECDH-ES" {
+		return key, nil, nil
+	}
+
+	cek, err = newCEK(header.Encryption)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedKey, err = aesKeyWrap(key, cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, encryptedKey, nil
+}
+
+// ecdhDecrypt восстанавливает ключ шифрования содержимого по эфемерному
+// публичному ключу из заголовка и закрытому ключу получателя.
+func ecdhDecrypt(header *jweHeader, priv *ecdsa.PrivateKey, encryptedKey []byte) ([]byte, error) {
+	size, err := ecdhKeySize(header.Algorithm, header.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	if header.EphemeralKey == nil {
+		return nil, errors.New("missing ephemeral public key")
+	}
+	epk, err := header.EphemeralKey.Decode()
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := epk.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("bad ephemeral public key")
+	}
+
+	apu, err := decodeSegment(header.PartyUInfo)
+	if err != nil {
+		return nil, err
+	}
+	apv, err := decodeSegment(header.PartyVInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	algID := header.Algorithm
+	if algID == "ECDH-ES" {
+		algID = header.Encryption
+	}
+	key, err := ecdhDeriveKey(priv, pub, algID, apu, apv, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Algorithm == "ECDH-ES" {
+		if len(encryptedKey) != 0 {
+			return nil, ErrInvalid // при прямом согласовании ключ не передается
+		}
+		return key, nil
+	}
+	return aesKeyUnwrap(key, encryptedKey)
+}
+
+// ecdhDeriveKey вычисляет общий секрет по ECDH и формирует из него ключ
+// заданной длины с помощью Concat KDF.
+func ecdhDeriveKey(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey, algID string, apu, apv []byte, size int) ([]byte, error) {
+	params := priv.Curve.Params()
+	if pub.Curve == nil || pub.Curve.Params().Name != params.Name {
+		return nil, errors.New("ecdh: curves mismatch")
+	}
+	// точка обязательно должна лежать на кривой, иначе возможно
+	// восстановление закрытого ключа получателя
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("ecdh: public key is not on curve")
+	}
+
+	x, _ := priv.Curve.ScalarMult(pub.X, pub.Y, priv.D.Bytes())
+	z := make([]byte, (params.BitSize+7)/8)
+	xb := x.Bytes()
+	copy(z[len(z)-len(xb):], xb)
+
+	return concatKDF(z, algID, apu, apv, size), nil
+}
+
+// concatKDF формирует ключ заданной длины из общего секрета z по алгоритму
+// Concat KDF (NIST SP 800-56A) с хеш-функцией SHA-256.
+func concatKDF(z []byte, algID string, apu, apv []byte, size int) []byte {
+	lengthPrefixed := func(data []byte) []byte {
+		result := make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(result, uint32(len(data)))
+		copy(result[4:], data)
+		return result
+	}
+
+	var otherInfo []byte
+	otherInfo = append(otherInfo, lengthPrefixed([]byte(algID))...)
+	otherInfo = append(otherInfo, lengthPrefixed(apu)...)
+	otherInfo = append(otherInfo, lengthPrefixed(apv)...)
+	suppPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(suppPubInfo, uint32(size*8))
+	otherInfo = append(otherInfo, suppPubInfo...)
+
+	var result []byte
+	for counter := uint32(1); len(result) < size; counter++ {
+		h := sha256.New()
+		_ = binary.Write(h, binary.BigEndian, counter)
+		_, _ = h.Write(z)
+		_, _ = h.Write(otherInfo)
+		result = h.Sum(result)
+	}
+
+	return result[:size]
+}
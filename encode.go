@@ -23,20 +23,34 @@ import (
 // 	func() string, interface{}
 // В последних случаях, кроме ключа, так же возвращается его идентификатор.
 func Encode(claimset, key interface{}) (string, error) {
+	return encode(claimset, key, &jwsHeader{Type: "JWT"})
+}
+
+// jwsHeader описывает заголовок формируемого подписанного токена.
+type jwsHeader struct {
+	Algorithm string `json:"alg"`             // алгоритм подписи
+	Type      string `json:"typ,omitempty"`   // тип токена
+	KeyID     string `json:"kid,omitempty"`   // необязательный идентификатор ключа
+	JWK       *JWK   `json:"jwk,omitempty"`   // публичный ключ для проверки подписи
+	Nonce     string `json:"nonce,omitempty"` // nonce сервера ACME
+	URL       string `json:"url,omitempty"`   // адрес запроса ACME
+}
+
+// encode возвращает подписанный токен с указанным заголовком. Алгоритм и
+// идентификатор ключа в заголовке задаются в соответствии с ключом.
+func encode(claimset, key interface{}, h *jwsHeader) (string, error) {
 	// кодируем данные токена в формат JSON
 	data, err := json.Marshal(claimset)
 	if err != nil {
 		return "", err
 	}
+	return encodePayload(data, key, h)
+}
 
+// encodePayload возвращает подписанный токен с произвольным содержимым.
+func encodePayload(data []byte, key interface{}, h *jwsHeader) (string, error) {
 	// если для получения ключа задана функция, то вызываем ее
-	var keyID string // идентификатор ключа
-	switch fkey := key.(type) {
-	case func() interface{}:
-		key = fkey()
-	case func() (string, interface{}):
-		keyID, key = fkey()
-	}
+	keyID, key := signingKey(key)
 
 	alg, hash := algorithm(key) // название алгоритма для подписи
 	if hash != 0 && !hash.Available() {
@@ -50,15 +64,8 @@ func Encode(claimset, key interface{}) (string, error) {
 	}
 
 	// формируем заголовок токена
-	header, err := json.Marshal(&struct {
-		Algorithm string `json:"alg"`           // алгоритм подписи
-		Type      string `json:"typ"`           // тип токена
-		KeyID     string `json:"kid,omitempty"` // необязательный идентификатор ключа
-	}{
-		Algorithm: alg,
-		Type:      "JWT",
-		KeyID:     keyID,
-	})
+	h.Algorithm, h.KeyID = alg, keyID
+	header, err := json.Marshal(h)
 	if err != nil {
 		return "", err
 	}
@@ -90,3 +97,15 @@ func Encode(claimset, key interface{}) (string, error) {
 
 	return token.String(), nil
 }
+
+// signingKey возвращает идентификатор и ключ для подписи. Если для получения
+// ключа задана функция, то она вызывается.
+func signingKey(key interface{}) (keyID string, _ interface{}) {
+	switch fkey := key.(type) {
+	case func() interface{}:
+		key = fkey()
+	case func() (string, interface{}):
+		keyID, key = fkey()
+	}
+	return keyID, key
+}
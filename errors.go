@@ -1,6 +1,11 @@
 package jwt
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Ошибки создания и верификации токенов.
 var (
@@ -8,8 +13,132 @@ var (
 	ErrInvalid         = errors.New("invalid token")
 	ErrBadType         = errors.New("bad token type")
 	ErrNotSigned       = errors.New("token not signed")
+	ErrBadSignature    = errors.New("bad token signature")
 	ErrCreatedAfterNow = errors.New("token created after now")
 	ErrNotBeforeNow    = errors.New("token not before now")
 	ErrExpired         = errors.New("token expired")
+	ErrMissingClaim    = errors.New("missing required claim")
+	ErrBadClaim        = errors.New("bad claim value")
+	ErrReplayed        = errors.New("token already used")
+	ErrRevoked         = errors.New("token revoked")
+	ErrAuthTooOld      = errors.New("authentication too old")
 	ErrBadHashFunc     = errors.New("hash function for key is not available")
+	ErrDecrypt         = errors.New("token decryption failed")
+	ErrIssuerMismatch  = errors.New("issuer mismatch")
+	ErrDisclosure      = errors.New("bad disclosure")
+)
+
+// ErrorKind описывает категорию ошибок проверки токена. Категории можно
+// объединять в битовую маску.
+type ErrorKind uint8
+
+// Категории ошибок проверки токена.
+const (
+	Malformed        ErrorKind = 1 << iota // токен не может быть разобран
+	SignatureInvalid                       // подпись токена не прошла проверку
+	ClaimsInvalid                          // поля токена не прошли проверку
 )
+
+// String возвращает строковое представление категорий ошибок.
+func (k ErrorKind) String() string {
+	var names []string
+	for kind, name := range []string{"malformed", "signature", "claims"} {
+		if k&(1<<kind) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// ValidationError описывает все ошибки, обнаруженные при проверке токена.
+// Поддерживает errors.Is и errors.As для каждой из сохраненных ошибок:
+//
+//	if errors.Is(err, jwt.ErrExpired) { ... }
+//
+//	var claimErr *jwt.ClaimError
+//	if errors.As(err, &claimErr) { ... }
+type ValidationError struct {
+	Kind   ErrorKind // категории обнаруженных ошибок
+	Errors []error   // все обнаруженные ошибки
+}
+
+// add добавляет ошибку указанной категории.
+func (e *ValidationError) add(kind ErrorKind, err error) {
+	e.Kind |= kind
+	e.Errors = append(e.Errors, err)
+}
+
+// merge добавляет ошибку, возвращенную при проверке полей токена. Ошибки
+// *ValidationError объединяются с текущими.
+func (e *ValidationError) merge(err error) {
+	if err == nil {
+		return
+	}
+	if verr, ok := err.(*ValidationError); ok {
+		e.Kind |= verr.Kind
+		e.Errors = append(e.Errors, verr.Errors...)
+		return
+	}
+	e.add(ClaimsInvalid, err)
+}
+
+// Has возвращает true, если среди ошибок есть ошибки указанной категории.
+func (e *ValidationError) Has(kind ErrorKind) bool {
+	return e.Kind&kind != 0
+}
+
+// Error возвращает описание всех обнаруженных ошибок.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is возвращает true, если хотя бы одна из ошибок соответствует target.
+func (e *ValidationError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As находит первую ошибку, соответствующую target.
+func (e *ValidationError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap возвращает список всех обнаруженных ошибок.
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// ClaimError описывает ошибку проверки отдельного поля токена.
+type ClaimError struct {
+	Claim string    // название поля токена
+	Time  time.Time // время из поля токена, если поле содержит дату
+	Now   time.Time // время проверки
+	Err   error     // причина ошибки
+}
+
+// Error возвращает описание ошибки.
+func (e *ClaimError) Error() string {
+	if e.Time.IsZero() {
+		return fmt.Sprintf("%s: %v", e.Claim, e.Err)
+	}
+	return fmt.Sprintf("%v (%s %s, now %s)", e.Err, e.Claim,
+		e.Time.UTC().Format(time.RFC3339), e.Now.UTC().Format(time.RFC3339))
+}
+
+// Unwrap возвращает причину ошибки.
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,170 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// IntrospectionType задает тип ответа на запрос интроспекции в формате JWT
+// (RFC 9701, раздел 5).
+const IntrospectionType = "token-introspection+jwt"
+
+// IntrospectionHandler реализует точку интроспекции токенов (RFC 7662) для
+// токенов, выпущенных с помощью этой библиотеки.
+//
+// Токен из параметра token проверяется с помощью Verify с ключом Key и
+// дополнительными проверками Validators (например, CheckRevocation). Если
+// задан Type, то в заголовке токена должен быть указан именно этот тип.
+// Для действительного токена возвращается "active": true и все поля токена,
+// для любого другого - только "active": false.
+//
+// RFC 7662 требует аутентификации обращающихся к точке интроспекции. Для
+// этого задается функция Authenticate, которая возвращает идентификатор
+// вызывающего сервера ресурсов или ошибку. Если функция не задана или
+// вернула пустой идентификатор, то на любой запрос возвращается ошибка 401.
+//
+// Если задан Response и клиент запрашивает тип
+// "application/token-introspection+jwt", то ответ возвращается в виде
+// подписанного токена в формате RFC 9701: получателем указывается
+// идентификатор вызывающего, а результат помещается в поле
+// "token_introspection".
+type IntrospectionHandler struct {
+	Key          interface{}                           // ключ для проверки токенов
+	Validators   []Validator                           // дополнительные проверки токена
+	Type         string                                // допустимый тип токена в заголовке
+	Authenticate func(r *http.Request) (string, error) // аутентификация вызывающего
+	Response     *Config                               // шаблон для подписанного ответа
+}
+
+// ServeHTTP обрабатывает запрос интроспекции токена.
+func (h *IntrospectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if h.Authenticate == nil {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication required")
+		return
+	}
+	caller, err := h.Authenticate(r)
+	if err == nil && caller == "" {
+		err = errors.New("client authentication required")
+	}
+	if err != nil {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "token required")
+		return
+	}
+
+	result := h.Introspect(token)
+	w.Header().Set("Cache-Control", "no-store")
+	if h.Response != nil && acceptsIntrospectionJWT(r) {
+		response, err := h.sign(caller, result)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/"+IntrospectionType)
+		_, _ = w.Write([]byte(response))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// Introspect проверяет токен и возвращает результат интроспекции: поле
+// "active" и, для действительного токена, все его поля.
+func (h *IntrospectionHandler) Introspect(token string) JSON {
+	if h.Key == nil {
+		return JSON{"active": false} // без проверки подписи токены не принимаются
+	}
+	parsed, err := parse(token, h.Key, h.Type, h.Validators)
+	if err != nil {
+		return JSON{"active": false}
+	}
+
+	result := make(JSON, len(parsed.Claims)+1)
+	for name, value := range parsed.Claims {
+		result[name] = value
+	}
+	result["active"] = true
+	return result
+}
+
+// sign возвращает подписанный ответ в формате RFC 9701. Получателем ответа
+// всегда указывается audience, даже если в шаблоне задан другой.
+func (h *IntrospectionHandler) sign(audience string, result JSON) (string, error) {
+	if h.Response.Key == nil {
+		return "", ErrEmptySignKey // неподписанный ответ не допускается
+	}
+	conf := *h.Response
+	conf.Created = true
+	conf.Encryption = nil
+	claims, err := conf.claims(JSON{"aud": audience, "token_introspection": result})
+	if err != nil {
+		return "", err
+	}
+	return encode(claims, conf.Key, &jwsHeader{Type: IntrospectionType})
+}
+
+// acceptsIntrospectionJWT возвращает true, если клиент запрашивает ответ в
+// формате RFC 9701.
+func acceptsIntrospectionJWT(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil &&
+			mediaType == "application/"+IntrospectionType {
+			return true
+		}
+	}
+	return false
+}
+
+// oauthError отправляет ответ с ошибкой в формате OAuth 2.0 (RFC 6749,
+// раздел 5.2).
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+	}{code, description})
+}
+
+// VerifyIntrospection проверяет подписанный ответ точки интроспекции в
+// формате RFC 9701 и возвращает результат интроспекции из поля
+// "token_introspection". Издателя и получателя ответа нужно проверить с
+// помощью validators.
+func VerifyIntrospection(response string, key interface{}, validators ...Validator) (JSON, error) {
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+	token, err := parse(response, key, IntrospectionType,
+		append([]Validator{RequireClaims("iss", "iat", "token_introspection")}, validators...))
+	if err != nil {
+		return nil, err
+	}
+	result, ok := token.Claims["token_introspection"].(JSON)
+	if !ok {
+		return nil, &ValidationError{Kind: ClaimsInvalid, Errors: []error{
+			&ClaimError{Claim: "token_introspection", Err: fmt.Errorf("%w: not an object", ErrBadClaim)}}}
+	}
+	return result, nil
+}
@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntrospectionHandler(t *testing.T) {
+	tokenKey, responseKey := NewES256Key(), NewES256Key()
+	revoked := NewRevocationList()
+	handler := &IntrospectionHandler{
+		Key:        &tokenKey.PublicKey,
+		Validators: []Validator{CheckRevocation(revoked)},
+		Type:       AccessTokenType,
+		Authenticate: func(r *http.Request) (string, error) {
+			id, secret, ok := r.BasicAuth()
+			if !ok || secret != "secret" {
+				return "", errors.New("bad credentials")
+			}
+			return id, nil
+		},
+		Response: &Config{Issuer: "https://as.example.com/", Key: responseKey},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	issuer := AccessTokenConfig{Config: Config{
+		Issuer:  "https://as.example.com/",
+		Expires: time.Hour,
+		Key:     tokenKey,
+	}}
+	token, err := issuer.Token(AccessToken{
+		Claims:   Claims{Subject: "Z5O3upPC88QrAjx00dis", Audience: Audience{"https://rs.example.com/"}},
+		ClientID: "l238j323ds-23ij4",
+		Scope:    "read write",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	introspect := func(token, accept, secret string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", server.URL,
+			strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("rs", secret)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, body := introspect(token, "", "secret")
+	var result JSON
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || result["active"] != true ||
+		result["client_id"] != "l238j323ds-23ij4" || result["scope"] != "read write" {
+		t.Errorf("bad introspection: %d %s", resp.StatusCode, body)
+	}
+
+	// ответ в формате JWT
+	resp, body = introspect(token, "application/token-introspection+jwt", "secret")
+	if ct := resp.Header.Get("Content-Type"); ct != "application/token-introspection+jwt" {
+		t.Fatalf("bad content type: %s", ct)
+	}
+	result, err = VerifyIntrospection(string(body), &responseKey.PublicKey,
+		IssuedBy("https://as.example.com/"), HasAudience("rs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["active"] != true || result["sub"] != "Z5O3upPC88QrAjx00dis" {
+		t.Errorf("bad introspection: %v", result)
+	}
+
+	// отозванные и чужие токены неактивны
+	claims, _ := VerifyAccessToken(token, &tokenKey.PublicKey)
+	revoked.RevokeID(claims.ID, claims.Expires.Time)
+	forged, _ := AccessTokenConfig{Config: Config{Issuer: "https://as.example.com/",
+		Expires: time.Hour, Key: NewES256Key()}}.Token(AccessToken{
+		Claims: Claims{Subject: "x", Audience: Audience{"rs"}}, ClientID: "c"})
+	for _, token := range []string{token, forged, "garbage"} {
+		_, body := introspect(token, "", "secret")
+		if strings.TrimSpace(string(body)) != `{"active":false}` {
+			t.Errorf("token is active: %s", body)
+		}
+	}
+
+	// вызывающий должен быть аутентифицирован
+	if resp, _ := introspect(token, "", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated introspection: %d", resp.StatusCode)
+	}
+	unauthenticated := &IntrospectionHandler{Key: &tokenKey.PublicKey}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	unauthenticated.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || strings.Contains(w.Body.String(), "active") {
+		t.Errorf("introspection without authentication: %d %s", w.Code, w.Body)
+	}
+
+	// без ключа подписанный ответ не формируется
+	handler.Response.Key = nil
+	resp, body = introspect(token, "application/token-introspection+jwt", "secret")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unsigned response: %d %s", resp.StatusCode, body)
+	}
+	handler.Response.Key = responseKey
+
+	// получатель подписанного ответа не подменяется шаблоном
+	handler.Response.Private = JSON{"aud": "https://other.example.com/"}
+	_, body = introspect(token, "application/token-introspection+jwt", "secret")
+	if _, err := VerifyIntrospection(string(body), &responseKey.PublicKey, HasAudience("rs")); err != nil {
+		t.Error("bad response audience:", err)
+	}
+}
@@ -0,0 +1,434 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Encryption описывает параметры шифрования данных в формате JWE (RFC 7516).
+//
+// Алгоритм управления ключом задается в Algorithm и зависит от ключа
+// получателя. Для *ecdsa.PublicKey поддерживаются ECDH-ES, ECDH-ES+A128KW,
+// ECDH-ES+A192KW и ECDH-ES+A256KW. Если алгоритм не указан, то используется
+// ECDH-ES.
+//
+// Для шифрования паролем, заданным в виде string, []byte или fmt.Stringer,
+// поддерживаются PBES2-HS256+A128KW, PBES2-HS384+A192KW и PBES2-HS512+A256KW.
+// По умолчанию используется PBES2-HS256+A128KW. Соль (p2s) и количество
+// итераций (p2c) задаются в Salt и Count. Если они не указаны, то соль
+// генерируется случайным образом, а количество итераций берется из
+// PBES2Count.
+//
+// Для шифрования содержимого поддерживаются A128GCM, A192GCM, A256GCM,
+// A128CBC-HS256, A192CBC-HS384 и A256CBC-HS512. По умолчанию - A128GCM.
+//
+// Чтобы указать в заголовке идентификатор ключа получателя, нужно чтобы
+// функция ключа возвращала два значения: первым будет KeyID, а вторым - сам
+// ключ.
+type Encryption struct {
+	Algorithm   string // alg - алгоритм управления ключом
+	Encryption  string // enc - алгоритм шифрования содержимого
+	ContentType string // cty - тип зашифрованного содержимого
+	PartyUInfo  []byte // apu - сведения об отправителе для ECDH-ES
+	PartyVInfo  []byte // apv - сведения о получателе для ECDH-ES
+	Salt        []byte // p2s - соль для PBES2
+	Count       int    // p2c - количество итераций для PBES2
+
+	Key interface{} // публичный ключ получателя или функция его возвращающая
+}
+
+// jweHeader описывает заголовок зашифрованного токена.
+type jweHeader struct {
+	Algorithm    string   `json:"alg"`           // алгоритм управления ключом
+	Encryption   string   `json:"enc"`           // алгоритм шифрования
+	Type         string   `json:"typ,omitempty"` // тип
+	ContentType  string   `json:"cty,omitempty"` // тип содержимого
+	KeyID        string   `json:"kid,omitempty"` // идентификатор ключа
+	EphemeralKey *JWK     `json:"epk,omitempty"` // эфемерный ключ ECDH-ES
+	PartyUInfo   string   `json:"apu,omitempty"` // сведения об отправителе
+	PartyVInfo   string   `json:"apv,omitempty"` // сведения о получателе
+	Salt         string   `json:"p2s,omitempty"` // соль PBES2
+	Count        int      `json:"p2c,omitempty"` // количество итераций PBES2
+	Compression  string   `json:"zip,omitempty"` // сжатие не поддерживается
+	Critical     []string `json:"crit,omitempty"`
+}
+
+// Encrypt шифрует payload для получателя и возвращает его в компактном
+// представлении JWE.
+func (e Encryption) Encrypt(payload []byte) (string, error) {
+	// если для получения ключа задана функция, то вызываем ее
+	key := e.Key
+	var keyID string // идентификатор ключа
+	switch fkey := key.(type) {
+	case func() interface{}:
+		key = fkey()
+	case func() (string, interface{}):
+		keyID, key = fkey()
+	}
+
+	header := &jweHeader{
+		Algorithm:   e.Algorithm,
+		Encryption:  e.Encryption,
+		ContentType: e.ContentType,
+		KeyID:       keyID,
+	}
+	if header.Encryption == "" {
+		header.Encryption = "A128GCM"
+	}
+	if len(e.PartyUInfo) > 0 {
+		header.PartyUInfo = base64.RawURLEncoding.EncodeToString(e.PartyUInfo)
+	}
+	if len(e.PartyVInfo) > 0 {
+		header.PartyVInfo = base64.RawURLEncoding.EncodeToString(e.PartyVInfo)
+	}
+
+	// формируем ключ шифрования в зависимости от типа ключа получателя
+	var cek, encryptedKey []byte
+	var err error
+	if privateKey, ok := key.(*ecdsa.PrivateKey); ok && privateKey != nil {
+		key = &privateKey.PublicKey // шифруем публичной частью ключа
+	}
+	switch recipientKey := key.(type) {
+	case *ecdsa.PublicKey:
+		if recipientKey == nil {
+			return "", ErrEmptySignKey
+		}
+		if header.Algorithm == "" {
+			header.Algorithm = "ECDH-ES"
+		}
+		cek, encryptedKey, err = ecdhEncrypt(header, recipientKey,
+			e.PartyUInfo, e.PartyVInfo)
+
+	case string, []byte, fmt.Stringer:
+		if header.Algorithm == "" {
+			header.Algorithm = "PBES2-HS256+A128KW"
+		}
+		cek, encryptedKey, err = pbes2Encrypt(header, password(key),
+			e.Salt, e.Count)
+
+	case nil:
+		return "", ErrEmptySignKey
+
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(data)
+
+	iv, ciphertext, tag, err := encryptContent(header.Encryption, cek,
+		payload, []byte(protected))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// Decrypt расшифровывает токен в формате JWE и возвращает его содержимое.
+// В качестве параметра передается закрытый ключ получателя или функция,
+// принимающая одно или два строковых значения (алгоритм и идентификатор
+// ключа) и возвращающая для них ключ:
+//
+//	func(alg string, keyID string) interface{}
+//	func(alg string) interface{}
+//
+// Для ECDH-ES в качестве ключа принимается *ecdsa.PrivateKey, а для PBES2 -
+// пароль в виде string, []byte или fmt.Stringer. Количество итераций PBES2
+// в токене ограничено значением PBES2MaxCount.
+func Decrypt(token string, key interface{}) ([]byte, error) {
+	_, payload, err := decrypt(token, key)
+	return payload, err
+}
+
+// decrypt расшифровывает токен и возвращает его заголовок и содержимое.
+func decrypt(token string, key interface{}) (*jweHeader, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, nil, ErrInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	header := new(jweHeader)
+	if err := json.Unmarshal(data, header); err != nil {
+		return nil, nil, err
+	}
+	if header.Compression != "" {
+		return nil, nil, fmt.Errorf("unsupported compression %q", header.Compression)
+	}
+	if len(header.Critical) > 0 {
+		return nil, nil, fmt.Errorf("unsupported critical header %q", header.Critical)
+	}
+
+	segments := make([][]byte, 4)
+	for i := range segments {
+		if segments[i], err = decodeSegment(parts[i+1]); err != nil {
+			return nil, nil, err
+		}
+	}
+	encryptedKey, iv, ciphertext, tag := segments[0], segments[1], segments[2], segments[3]
+
+	// если для получения ключа задана функция, то вызываем ее
+	switch fkey := key.(type) {
+	case func(string, string) interface{}:
+		key = fkey(header.Algorithm, header.KeyID)
+	case func(string) interface{}:
+		key = fkey(header.Algorithm)
+	}
+
+	if key == nil {
+		return nil, nil, ErrEmptySignKey
+	} else if err, ok := key.(error); ok {
+		return nil, nil, err
+	}
+
+	// восстанавливаем ключ шифрования содержимого
+	var cek []byte
+	switch recipientKey := key.(type) {
+	case *ecdsa.PrivateKey:
+		if !strings.HasPrefix(header.Algorithm, "ECDH-ES") {
+			return nil, nil, fmt.Errorf("unsupported key management algorithm %q for key type %T",
+				header.Algorithm, key)
+		}
+		cek, err = ecdhDecrypt(header, recipientKey, encryptedKey)
+
+	case string, []byte, fmt.Stringer:
+		if !strings.HasPrefix(header.Algorithm, "PBES2-") {
+			return nil, nil, fmt.Errorf("unsupported key management algorithm %q for key type %T",
+				header.Algorithm, key)
+		}
+		cek, err = pbes2Decrypt(header, password(key), encryptedKey)
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := decryptContent(header.Encryption, cek, iv, ciphertext, tag,
+		[]byte(parts[0]))
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, payload, nil
+}
+
+// password возвращает пароль в бинарном виде.
+func password(key interface{}) []byte {
+	switch key := key.(type) {
+	case []byte:
+		return key
+	case string:
+		return []byte(key)
+	case fmt.Stringer:
+		return []byte(key.String())
+	default:
+		return nil
+	}
+}
+
+// decodeSegment декодирует часть токена из формата base64.
+func decodeSegment(segment string) ([]byte, error) {
+	if segment == "" {
+		return nil, nil
+	}
+	return base64.RawURLEncoding.DecodeString(segment)
+}
+
+// cekSize возвращает размер ключа для указанного алгоритма шифрования
+// содержимого.
+func cekSize(enc string) (int, error) {
+	switch enc {
+	case "A128GCM":
+		return 16, nil
+	case "A192GCM":
+		return 24, nil
+	case "A256GCM", "A128CBC-HS256":
+		return 32, nil
+	case "A192CBC-HS384":
+		return 48, nil
+	case "A256CBC-HS512":
+		return 64, nil
+	default:
+		return 0, fmt.Errorf("unsupported content encryption algorithm %q", enc)
+	}
+}
+
+// newCEK возвращает случайный ключ шифрования содержимого для указанного
+// алгоритма.
+func newCEK(enc string) ([]byte, error) {
+	size, err := cekSize(enc)
+	if err != nil {
+		return nil, err
+	}
+	cek := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+		return nil, err
+	}
+	return cek, nil
+}
+
+// cbcHash возвращает хеш-функцию для алгоритмов AES_CBC_HMAC_SHA2.
+func cbcHash(enc string) func() hash.Hash {
+	switch enc {
+	case "A128CBC-HS256":
+		return sha256.New
+	case "A192CBC-HS384":
+		return sha512.New384
+	default:
+		return sha512.New
+	}
+}
+
+// encryptContent шифрует содержимое токена ключом cek. Дополнительные данные
+// aad участвуют в вычислении кода аутентичности.
+func encryptContent(enc string, cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	size, err := cekSize(enc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(cek) != size {
+		return nil, nil, nil, ErrInvalid
+	}
+
+	if strings.HasSuffix(enc, "GCM") {
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		iv = make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+			return nil, nil, nil, err
+		}
+		sealed := aead.Seal(nil, iv, plaintext, aad)
+		n := len(sealed) - aead.Overhead()
+		return iv, sealed[:n], sealed[n:], nil
+	}
+
+	// AES_CBC_HMAC_SHA2: первая половина ключа для HMAC, вторая - для AES
+	macKey, encKey := cek[:size/2], cek[size/2:]
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	iv = make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, nil, nil, err
+	}
+	// дополняем данные по PKCS#7
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	ciphertext = make([]byte, len(plaintext)+padding)
+	copy(ciphertext, plaintext)
+	for i := len(plaintext); i < len(ciphertext); i++ {
+		ciphertext[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	tag = cbcTag(cbcHash(enc), macKey, aad, iv, ciphertext)
+
+	return iv, ciphertext, tag, nil
+}
+
+// decryptContent расшифровывает содержимое токена и проверяет его
+// целостность.
+func decryptContent(enc string, cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	size, err := cekSize(enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(cek) != size {
+		return nil, ErrDecrypt
+	}
+
+	if strings.HasSuffix(enc, "GCM") {
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != aead.NonceSize() || len(tag) != aead.Overhead() {
+			return nil, ErrDecrypt
+		}
+		sealed := append(append([]byte{}, ciphertext...), tag...)
+		plaintext, err := aead.Open(nil, iv, sealed, aad)
+		if err != nil {
+			return nil, ErrDecrypt
+		}
+		return plaintext, nil
+	}
+
+	macKey, encKey := cek[:size/2], cek[size/2:]
+	expected := cbcTag(cbcHash(enc), macKey, aad, iv, ciphertext)
+	if subtle.ConstantTimeCompare(expected, tag) != 1 {
+		return nil, ErrDecrypt
+	}
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 ||
+		len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrDecrypt
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrDecrypt
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, ErrDecrypt
+		}
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// cbcTag вычисляет код аутентичности для AES_CBC_HMAC_SHA2.
+func cbcTag(h func() hash.Hash, macKey, aad, iv, ciphertext []byte) []byte {
+	al := make([]byte, 8)
+	binary.BigEndian.PutUint64(al, uint64(len(aad))*8)
+
+	mac := hmac.New(h, macKey)
+	_, _ = mac.Write(aad)
+	_, _ = mac.Write(iv)
+	_, _ = mac.Write(ciphertext)
+	_, _ = mac.Write(al)
+	return mac.Sum(nil)[:len(macKey)]
+}
@@ -0,0 +1,167 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestECDHConcatKDF(t *testing.T) {
+	// RFC 7518, Appendix C
+	var alice, bob JWK
+	if err := json.Unmarshal([]byte(`{"kty":"EC","crv":"P-256",
+		"x":"gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0",
+		"y":"SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps",
+		"d":"0_NxaRPUMQoAJt50Gz8YiTr8gRTwyEaCumd-MToTmIo"}`), &alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"kty":"EC","crv":"P-256",
+		"x":"weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ",
+		"y":"e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck",
+		"d":"VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw"}`), &bob); err != nil {
+		t.Fatal(err)
+	}
+	aliceKey, err := alice.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobKey, err := bob.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdhDeriveKey(aliceKey.(*ecdsa.PrivateKey),
+		&bobKey.(*ecdsa.PrivateKey).PublicKey,
+		"A128GCM", []byte("Alice"), []byte("Bob"), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.RawURLEncoding.EncodeToString(key); got != "VqqN6vgjbSBcIijNcacQGg" {
+		t.Errorf("bad derived key: %s", got)
+	}
+}
+
+func TestAESKeyWrap(t *testing.T) {
+	// RFC 3394, 4.1
+	kek := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F}
+	cek := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77,
+		0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	expected := []byte{0x1F, 0xA6, 0x8B, 0x0A, 0x81, 0x12, 0xB4, 0x47,
+		0xAE, 0xF3, 0x4B, 0xD8, 0xFB, 0x5A, 0x7B, 0x82,
+		0x9D, 0x3E, 0x86, 0x23, 0x71, 0xD2, 0xCF, 0xE5}
+
+	wrapped, err := aesKeyWrap(kek, cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(wrapped, expected) {
+		t.Fatalf("bad wrapped key: %x", wrapped)
+	}
+	unwrapped, err := aesKeyUnwrap(kek, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, cek) {
+		t.Fatalf("bad unwrapped key: %x", unwrapped)
+	}
+	wrapped[0] ^= 1
+	if _, err := aesKeyUnwrap(kek, wrapped); err != ErrDecrypt {
+		t.Fatal("corrupted key unwrapped")
+	}
+}
+
+func TestEncryptECDH(t *testing.T) {
+	payload := []byte(`{"sub":"9394203942934"}`)
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, alg := range []string{"ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW"} {
+			for _, enc := range []string{"A128GCM", "A256GCM", "A128CBC-HS256", "A256CBC-HS512"} {
+				e := Encryption{
+					Algorithm:  alg,
+					Encryption: enc,
+					PartyUInfo: []byte("Alice"),
+					PartyVInfo: []byte("Bob"),
+					Key:        &key.PublicKey,
+				}
+				token, err := e.Encrypt(payload)
+				if err != nil {
+					t.Fatal(alg, enc, err)
+				}
+				data, err := Decrypt(token, key)
+				if err != nil {
+					t.Fatal(alg, enc, err)
+				}
+				if !bytes.Equal(data, payload) {
+					t.Fatal(alg, enc, "bad decrypted payload")
+				}
+			}
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	key := NewES256Key()
+	jwk, err := JWKEncode(key, "enc-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := jwk.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := Encryption{
+		Algorithm: "ECDH-ES+A128KW",
+		Key: func() (string, interface{}) {
+			return "enc-key", restored.(*ecdsa.PrivateKey).Public()
+		},
+	}
+	token, err := e.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getKey := func(alg, keyID string) interface{} {
+		if alg != "ECDH-ES+A128KW" || keyID != "enc-key" {
+			return nil
+		}
+		return key
+	}
+	if data, err := Decrypt(token, getKey); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "secret" {
+		t.Fatal("bad decrypted payload")
+	}
+
+	if _, err := Decrypt(token, NewES256Key()); err != ErrDecrypt {
+		t.Error("decrypted with wrong key:", err)
+	}
+
+	parts := strings.Split(token, ".")
+	parts[3] = base64.RawURLEncoding.EncodeToString([]byte("tampered"))
+	if _, err := Decrypt(strings.Join(parts, "."), key); err != ErrDecrypt {
+		t.Error("decrypted tampered token:", err)
+	}
+
+	// эфемерный ключ, не лежащий на кривой, должен отвергаться
+	header := new(jweHeader)
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(data, header); err != nil {
+		t.Fatal(err)
+	}
+	header.EphemeralKey.Y = header.EphemeralKey.X
+	data, _ = json.Marshal(header)
+	parts[0] = base64.RawURLEncoding.EncodeToString(data)
+	if _, err := Decrypt(strings.Join(parts, "."), key); err == nil {
+		t.Error("invalid curve point accepted")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
@@ -86,7 +88,7 @@ type JWK struct {
 	KeyOps []string `json:"key_ops,omitempty"`
 	// The "alg" (algorithm) parameter identifies the algorithm intended for
 	// use with the key.
-	Algorithm string `json:"alg"`
+	Algorithm string `json:"alg,omitempty"`
 	// The "kid" (key ID) parameter is used to match a specific key.  This
 	// is used, for instance, to choose among a set of keys within a JWK Set
 	// during key rollover.
@@ -316,12 +318,12 @@ func (key *JWK) Decode() (interface{}, error) {
 	case key.Type == "EC" ||
 		(key.Curve != "" && key.X != "" && key.Y != ""):
 
-		x, err := base64.RawURLEncoding.DecodeString(key.E)
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
 		if err != nil {
 			return nil, err
 		}
 
-		y, err := base64.RawURLEncoding.DecodeString(key.N)
+		y, err := base64.RawURLEncoding.DecodeString(key.Y)
 		if err != nil {
 			return nil, err
 		}
@@ -334,6 +336,8 @@ func (key *JWK) Decode() (interface{}, error) {
 			crv = elliptic.P384()
 		case "P-521":
 			crv = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve: %q", key.Curve)
 		}
 
 		ecdsaKey := &ecdsa.PublicKey{
@@ -362,3 +366,78 @@ func (key *JWK) Decode() (interface{}, error) {
 		return nil, fmt.Errorf("unsupported key type: %T", key)
 	}
 }
+
+// Thumbprint возвращает отпечаток ключа, вычисленный с помощью SHA-256 по
+// правилам RFC 7638 и закодированный в base64. Для закрытого ключа отпечаток
+// совпадает с отпечатком соответствующего ему открытого ключа.
+func (key *JWK) Thumbprint() (string, error) {
+	var members interface{} // обязательные поля ключа в алфавитном порядке
+	switch key.Type {
+	case "EC":
+		members = struct {
+			Curve string `json:"crv"`
+			Type  string `json:"kty"`
+			X     string `json:"x"`
+			Y     string `json:"y"`
+		}{key.Curve, key.Type, key.X, key.Y}
+	case "RSA":
+		members = struct {
+			E    string `json:"e"`
+			Type string `json:"kty"`
+			N    string `json:"n"`
+		}{key.E, key.Type, key.N}
+	case "oct":
+		members = struct {
+			K    string `json:"k"`
+			Type string `json:"kty"`
+		}{key.K, key.Type}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", key.Type)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// signerJWK возвращает открытый ключ для закрытого ключа подписи RSA или
+// ECDSA в формате JWK без указания назначения и алгоритма, как он
+// передается в заголовке jwk.
+func signerJWK(key interface{}) (*JWK, error) {
+	var public interface{}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		public = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		public = &key.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	jwk, err := JWKEncode(public, "")
+	if err != nil {
+		return nil, err
+	}
+	jwk.Usage, jwk.Algorithm = "", ""
+	return jwk, nil
+}
+
+// publicKey возвращает открытый ключ RSA или ECDSA из JWK, полученного от
+// другой стороны. Закрытые и симметричные ключи не принимаются.
+func (key *JWK) publicKey() (interface{}, error) {
+	if key.D != "" || key.K != "" {
+		return nil, fmt.Errorf("%w: private key in jwk", ErrInvalid)
+	}
+	public, err := key.Decode()
+	if err != nil {
+		return nil, err
+	}
+	switch public.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return public, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported jwk type %q", ErrInvalid, key.Type)
+	}
+}
@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Keys описывает набор публичных ключей в формате JWKS (RFC 7517, раздел 5).
+// Метод Key можно использовать в качестве функции ключа для Verify:
+//
+//	claim, err := jwt.Verify(token, keys.Key)
+type Keys struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// ReadKeys читает набор ключей в формате JWKS.
+func ReadKeys(r io.Reader) (*Keys, error) {
+	keys := new(Keys)
+	if err := json.NewDecoder(r).Decode(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Lookup возвращает описание ключа для проверки подписи с указанным
+// алгоритмом и идентификатором. Если идентификатор не задан, то ключ
+// возвращается только в том случае, если он единственный подходящий.
+func (k *Keys) Lookup(alg, keyID string) (*JWK, error) {
+	var found *JWK
+	for _, jwk := range k.Keys {
+		if keyID != "" && jwk.ID != keyID {
+			continue
+		}
+		if jwk.Usage != "" && jwk.Usage != "sig" {
+			continue // ключ не предназначен для подписи
+		}
+		if alg != "" && jwk.Algorithm != "" && jwk.Algorithm != alg {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous key for alg %q and kid %q", alg, keyID)
+		}
+		found = jwk
+	}
+	if found == nil {
+		return nil, fmt.Errorf("key for alg %q and kid %q not found", alg, keyID)
+	}
+	return found, nil
+}
+
+// Key возвращает ключ для проверки подписи с указанным алгоритмом и
+// идентификатором. В случае ошибки возвращается сама ошибка, что позволяет
+// использовать метод в качестве функции ключа для Verify.
+func (k *Keys) Key(alg, keyID string) interface{} {
+	jwk, err := k.Lookup(alg, keyID)
+	if err != nil {
+		return err
+	}
+	key, err := jwk.Decode()
+	if err != nil {
+		return err
+	}
+	return key
+}
@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// defaultIV задает начальное значение для алгоритма AES Key Wrap (RFC 3394).
+var defaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// aesKeyWrap шифрует ключ cek ключом kek по алгоритму AES Key Wrap.
+func aesKeyWrap(kek, cek []byte) ([]byte, error) {
+	if len(cek)%8 != 0 || len(cek) < 16 {
+		return nil, errors.New("key wrap: bad key length")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(cek) / 8
+	result := make([]byte, (n+1)*8)
+	copy(result[8:], cek)
+	a := make([]byte, 8)
+	copy(a, defaultIV)
+
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], a)
+			copy(buf[8:], result[i*8:(i+1)*8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(result[i*8:(i+1)*8], buf[8:])
+		}
+	}
+	copy(result[:8], a)
+
+	return result, nil
+}
+
+// aesKeyUnwrap расшифровывает ключ, зашифрованный по алгоритму AES Key Wrap,
+// и проверяет его целостность.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("key wrap: bad wrapped key length")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	result := make([]byte, n*8)
+	copy(result, wrapped[8:])
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], result[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(result[(i-1)*8:i*8], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, defaultIV) != 1 {
+		return nil, ErrDecrypt
+	}
+
+	return result, nil
+}
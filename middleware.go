@@ -0,0 +1,259 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor описывает функцию, которая извлекает токен из HTTP-запроса.
+// Если токен не найден, то возвращается пустая строка.
+type TokenExtractor func(r *http.Request) string
+
+// TokenFromHeader извлекает токен из заголовка Authorization со схемой
+// Bearer (RFC 6750, раздел 2.1).
+func TokenFromHeader(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// TokenFromCookie возвращает функцию, которая извлекает токен из cookie с
+// указанным именем.
+func TokenFromCookie(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+// TokenFromQuery возвращает функцию, которая извлекает токен из параметра
+// запроса с указанным именем (RFC 6750, раздел 2.3 использует имя
+// "access_token").
+func TokenFromQuery(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+// Authenticator проверяет токены в HTTP-запросах.
+//
+// Токен извлекается из запроса с помощью функций Extractors. Если они не
+// заданы, то используется только заголовок Authorization. Если токен найден
+// сразу в нескольких местах, то запрос отвергается, как того требует
+// RFC 6750.
+//
+// Токен проверяется с помощью Verify с ключом Key и дополнительными
+// проверками Validators. Если задан Type, то в заголовке токена должен быть
+// указан именно этот тип, например "at+jwt" для токенов доступа RFC 9068.
+// Если задан Scope, то дополнительно проверяется, что поле токена "scope"
+// содержит все перечисленные значения.
+//
+// В случае ошибки возвращается ответ с заголовком WWW-Authenticate в формате
+// RFC 6750. Если не задан ключ Key, то это считается ошибкой настройки
+// сервера и возвращается код 500.
+type Authenticator struct {
+	Key        interface{}      // ключ для проверки подписи или функция его возвращающая
+	Validators []Validator      // дополнительные проверки токена
+	Extractors []TokenExtractor // способы извлечения токена из запроса
+	Scope      []string         // обязательные значения scope
+	Realm      string           // realm для заголовка WWW-Authenticate
+	Type       string           // допустимый тип токена в заголовке
+}
+
+// Handler возвращает обработчик HTTP-запросов, который пропускает к next
+// только запросы с действительным токеном. Разобранный токен сохраняется в
+// контексте запроса и доступен через TokenFromContext и ClaimsFromContext.
+func (a *Authenticator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := a.Token(r)
+		if err != nil {
+			a.Error(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithToken(r.Context(), token)))
+	})
+}
+
+// Token извлекает токен из запроса, проверяет его и возвращает в
+// разобранном виде.
+func (a *Authenticator) Token(r *http.Request) (*Token, error) {
+	extractors := a.Extractors
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{TokenFromHeader}
+	}
+
+	var raw string
+	for _, extractor := range extractors {
+		if token := extractor(r); token != "" {
+			if raw != "" {
+				return nil, errMultipleTokens
+			}
+			raw = token
+		}
+	}
+	if raw == "" {
+		return nil, errNoToken
+	}
+	if a.Key == nil {
+		return nil, ErrEmptySignKey // без проверки подписи токены не принимаются
+	}
+
+	// scope проверяется вместе с остальными проверками, чтобы отложенные
+	// действия (PreventReplay) не выполнялись для токена без нужных прав
+	validators := a.Validators
+	if len(a.Scope) > 0 {
+		scope := ClaimContains("scope", a.Scope...)
+		validators = append([]Validator{func(token *Token) error {
+			if err := scope(token); err != nil {
+				return &insufficientScopeError{err: err}
+			}
+			return nil
+		}}, a.Validators...)
+	}
+	token, err := parse(raw, a.Key, a.Type, validators)
+	if err != nil {
+		return nil, scopeError(err)
+	}
+	return token, nil
+}
+
+// scopeError возвращает ошибку недостаточных прав, если токен отвергнут
+// только из-за нее. Иначе токен считается недействительным, и ошибка прав из
+// списка удаляется.
+func scopeError(err error) error {
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		return err
+	}
+	var scopeErr error
+	errs := make([]error, 0, len(verr.Errors))
+	for _, err := range verr.Errors {
+		if _, ok := err.(*insufficientScopeError); ok {
+			scopeErr = err
+			continue
+		}
+		errs = append(errs, err)
+	}
+	switch {
+	case scopeErr == nil:
+		return err
+	case len(errs) == 0:
+		return scopeErr
+	}
+	verr.Errors = errs
+	return verr
+}
+
+// Error отправляет ответ с ошибкой авторизации в формате RFC 6750. Описание
+// ошибки клиенту передается в общем виде, без подробностей проверки.
+func (a *Authenticator) Error(w http.ResponseWriter, err error) {
+	if err == ErrEmptySignKey {
+		// ошибка настройки сервера, а не токена
+		http.Error(w, http.StatusText(http.StatusInternalServerError),
+			http.StatusInternalServerError)
+		return
+	}
+
+	params := make([]string, 0, 4)
+	if a.Realm != "" {
+		params = append(params, authParam("realm", a.Realm))
+	}
+
+	status := http.StatusUnauthorized
+	var scopeErr *insufficientScopeError
+	switch {
+	case errors.Is(err, errNoToken):
+		// запрос без токена: код ошибки не указывается
+
+	case errors.Is(err, errMultipleTokens):
+		status = http.StatusBadRequest
+		params = append(params, authParam("error", "invalid_request"),
+			authParam("error_description", "multiple tokens in request"))
+
+	case errors.As(err, &scopeErr):
+		status = http.StatusForbidden
+		params = append(params, authParam("error", "insufficient_scope"),
+			authParam("error_description", "insufficient scope"),
+			authParam("scope", strings.Join(a.Scope, " ")))
+
+	default:
+		description := "invalid token"
+		if errors.Is(err, ErrExpired) {
+			description = "token expired"
+		}
+		params = append(params, authParam("error", "invalid_token"),
+			authParam("error_description", description))
+	}
+
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// Ошибки извлечения токена из запроса.
+var (
+	errNoToken        = errors.New("no token in request")
+	errMultipleTokens = errors.New("multiple tokens in request")
+)
+
+// insufficientScopeError описывает ошибку недостаточных прав токена.
+type insufficientScopeError struct {
+	err error
+}
+
+func (e *insufficientScopeError) Error() string { return "insufficient scope" }
+func (e *insufficientScopeError) Unwrap() error { return e.err }
+
+// authParam возвращает параметр заголовка WWW-Authenticate. Символы, которые
+// не допускаются в значениях RFC 6750, заменяются.
+func authParam(name, value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r < 0x20 || r > 0x7e {
+			return '\''
+		}
+		return r
+	}, value)
+	return fmt.Sprintf("%s=%q", name, value)
+}
+
+// tokenContextKey задает ключ для хранения токена в контексте.
+type tokenContextKey struct{}
+
+// ContextWithToken возвращает контекст с сохраненным в нем токеном.
+func ContextWithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// TokenFromContext возвращает токен, сохраненный в контексте.
+func TokenFromContext(ctx context.Context) (*Token, bool) {
+	token, ok := ctx.Value(tokenContextKey{}).(*Token)
+	return token, ok
+}
+
+// ClaimsFromContext возвращает содержимое токена, сохраненного в контексте,
+// распакованное в объект указанного типа.
+//
+//	claims, err := jwt.ClaimsFromContext[MyClaims](r.Context())
+func ClaimsFromContext[T any](ctx context.Context) (*T, error) {
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		return nil, errNoToken
+	}
+	claims := new(T)
+	if err := json.Unmarshal(token.Raw, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
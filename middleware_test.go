@@ -0,0 +1,126 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticator(t *testing.T) {
+	key := NewES256Key()
+	conf := Config{
+		Issuer:  "http://service.example.com/",
+		Expires: time.Hour,
+		Key:     key,
+	}
+	token, err := conf.Token(JSON{"sub": "9394203942934", "scope": "read write"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := &Authenticator{
+		Key:        &key.PublicKey,
+		Validators: []Validator{IssuedBy(conf.Issuer)},
+		Extractors: []TokenExtractor{
+			TokenFromHeader,
+			TokenFromCookie("token"),
+			TokenFromQuery("access_token"),
+		},
+		Scope: []string{"read"},
+		Realm: "example",
+	}
+	handler := auth.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := ClaimsFromContext[Claims](r.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(claims.Subject))
+	}))
+
+	for _, test := range []struct {
+		name      string
+		prepare   func(r *http.Request)
+		status    int
+		challenge string
+	}{
+		{"header", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}, http.StatusOK, ""},
+		{"cookie", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: "token", Value: token})
+		}, http.StatusOK, ""},
+		{"query", func(r *http.Request) {
+			r.URL.RawQuery = "access_token=" + token
+		}, http.StatusOK, ""},
+		{"missing", func(r *http.Request) {}, http.StatusUnauthorized,
+			`Bearer realm="example"`},
+		{"multiple", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+token)
+			r.URL.RawQuery = "access_token=" + token
+		}, http.StatusBadRequest, `error="invalid_request"`},
+		{"invalid", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+token+"x")
+		}, http.StatusUnauthorized, `error="invalid_token", error_description="invalid token"`},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		test.prepare(req)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s: bad status %d", test.name, rec.Code)
+		}
+		challenge := rec.Header().Get("WWW-Authenticate")
+		if !strings.Contains(challenge, test.challenge) ||
+			(test.challenge == "") != (challenge == "") {
+			t.Errorf("%s: bad challenge %q", test.name, challenge)
+		}
+		if test.status == http.StatusOK && rec.Body.String() != "9394203942934" {
+			t.Errorf("%s: bad body %q", test.name, rec.Body.String())
+		}
+	}
+
+	// недостаточные права
+	auth.Scope = []string{"admin"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden ||
+		!strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope", `) {
+		t.Errorf("bad insufficient scope response: %d %q", rec.Code,
+			rec.Header().Get("WWW-Authenticate"))
+	}
+
+	// токен без нужных прав не запоминается, а недействительный токен
+	// отвергается как недействительный, даже если прав недостаточно
+	cache := NewReplayCache(10, 0)
+	conf.UniqueID = Nonce(8)
+	withID, _ := conf.Token(JSON{"sub": "9394203942934", "scope": "read"})
+	auth.Validators = []Validator{PreventReplay(cache), IssuedBy(conf.Issuer)}
+	req.Header.Set("Authorization", "Bearer "+withID)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || cache.Len() != 0 {
+		t.Errorf("token without scope remembered: %d %d", rec.Code, cache.Len())
+	}
+	auth.Validators = []Validator{IssuedBy("http://other.example.com/")}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized ||
+		!strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Errorf("bad invalid token response: %d %q", rec.Code,
+			rec.Header().Get("WWW-Authenticate"))
+	}
+
+	// без ключа запросы отвергаются как ошибка настройки сервера
+	misconfigured := &Authenticator{}
+	rec = httptest.NewRecorder()
+	misconfigured.Handler(handler).ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("bad response without key: %d %q", rec.Code,
+			rec.Header().Get("WWW-Authenticate"))
+	}
+}
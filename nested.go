@@ -0,0 +1,42 @@
+package jwt
+
+import "strings"
+
+// VerifyNested расшифровывает вложенный токен (Nested JWT), проверяет, что он
+// содержит подписанный токен, и проверяет его подпись и даты так же, как это
+// делает Verify, включая дополнительные проверки validators.
+//
+// В качестве decryptKey передается ключ для расшифровки в формате,
+// поддерживаемом Decrypt, а в качестве key - ключ для проверки подписи в
+// формате, поддерживаемом Verify.
+//
+// Возвращается неразобранное содержимое вложенного токена.
+func VerifyNested(token string, decryptKey, key interface{}, validators ...Validator) ([]byte, error) {
+	if key == nil {
+		return nil, ErrEmptySignKey // подпись вложенного токена проверяется всегда
+	}
+
+	inner, err := decryptNested(token, decryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return Verify(inner, key, validators...)
+}
+
+// decryptNested расшифровывает вложенный токен и возвращает подписанный
+// токен, который в нем содержится.
+func decryptNested(token string, decryptKey interface{}) (string, error) {
+	header, payload, err := decrypt(token, decryptKey)
+	if err != nil {
+		return "", err
+	}
+
+	// проверяем, что зашифрован именно токен
+	cty := strings.TrimPrefix(strings.ToLower(header.ContentType), "application/")
+	if cty != "jwt" {
+		return "", ErrBadType
+	}
+
+	return string(payload), nil
+}
@@ -0,0 +1,220 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IDToken описывает содержимое ID-токена OpenID Connect (OpenID Connect
+// Core 1.0, раздел 2).
+type IDToken struct {
+	Claims
+	Nonce           string   `json:"nonce,omitempty"`     // значение nonce из запроса
+	AuthTime        Time     `json:"auth_time,omitempty"` // время аутентификации
+	ACR             string   `json:"acr,omitempty"`       // класс аутентификации
+	AMR             []string `json:"amr,omitempty"`       // методы аутентификации
+	AuthorizedParty string   `json:"azp,omitempty"`       // авторизованная сторона
+	AccessTokenHash string   `json:"at_hash,omitempty"`   // хеш токена доступа
+	CodeHash        string   `json:"c_hash,omitempty"`    // хеш кода авторизации
+}
+
+// IDTokenVerifier проверяет ID-токены OpenID Connect в соответствии с
+// OpenID Connect Core 1.0, раздел 3.1.3.7.
+//
+// Проверяется, что издатель (iss) совпадает с Issuer, среди получателей
+// (aud) есть ClientID, а остальные получатели перечислены в
+// TrustedAudiences. Если получателей несколько, то обязательно поле azp,
+// которое должно совпадать с ClientID. Поля exp и iat обязательны.
+// Токен должен быть подписан одним из алгоритмов Algorithms (по умолчанию
+// RS256).
+//
+// Если задано MaxAge, то обязательно поле auth_time и время аутентификации
+// не должно быть старше MaxAge. Если задан ACRValues, то значение acr должно
+// быть одним из них.
+//
+// Если задан DecryptKey, то принимаются и зашифрованные ID-токены: они
+// расшифровываются с помощью Decrypt перед проверкой.
+type IDTokenVerifier struct {
+	Issuer           string        // идентификатор провайдера
+	ClientID         string        // идентификатор клиента
+	Key              interface{}   // ключ для проверки подписи или функция его возвращающая
+	Algorithms       []string      // допустимые алгоритмы подписи
+	TrustedAudiences []string      // допустимые дополнительные получатели
+	MaxAge           time.Duration // максимальное время с момента аутентификации
+	ACRValues        []string      // допустимые значения acr
+	DecryptKey       interface{}   // ключ для расшифровки токена
+}
+
+// Verify проверяет ID-токен и возвращает его содержимое. Если при
+// авторизации передавался nonce, то его нужно указать для проверки, иначе
+// передается пустая строка. Для проверки хешей at_hash и c_hash добавьте
+// проверки AccessTokenHash и CodeHash.
+func (v *IDTokenVerifier) Verify(token, nonce string, validators ...Validator) (*IDToken, error) {
+	if v.DecryptKey != nil && strings.Count(token, ".") == 4 {
+		inner, err := decryptNested(token, v.DecryptKey)
+		if err != nil {
+			return nil, err
+		}
+		token = inner
+	}
+
+	return VerifyClaims[IDToken](token, v.Key,
+		append([]Validator{v.Validator(nonce)}, validators...)...)
+}
+
+// Validator возвращает проверку ID-токена, которую можно использовать
+// вместе с VerifyClaims для распаковки токена в собственную структуру.
+func (v *IDTokenVerifier) Validator(nonce string) Validator {
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256"}
+	}
+
+	validators := []Validator{
+		AllowAlgorithms(algorithms...),
+		RequireClaims("iss", "sub", "aud", "exp", "iat"),
+		IssuedBy(v.Issuer),
+		v.checkAudience,
+	}
+	if nonce != "" {
+		validators = append(validators, ClaimEquals("nonce", nonce))
+	}
+	if v.MaxAge > 0 {
+		validators = append(validators, v.checkAuthTime)
+	}
+	if len(v.ACRValues) > 0 {
+		validators = append(validators, CheckClaim("acr", func(value interface{}) error {
+			for _, acr := range v.ACRValues {
+				if value == acr {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %v", ErrBadClaim, value)
+		}))
+	}
+
+	return All(validators...)
+}
+
+// checkAudience проверяет получателей токена и поле azp.
+func (v *IDTokenVerifier) checkAudience(token *Token) error {
+	audience := claimAudience(token.Claims["aud"])
+	found := false
+	for _, aud := range audience {
+		if aud == v.ClientID {
+			found = true
+			continue
+		}
+		trusted := false
+		for _, trustedAud := range v.TrustedAudiences {
+			if aud == trustedAud {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			return &ClaimError{Claim: "aud",
+				Err: fmt.Errorf("%w: untrusted audience %q", ErrBadClaim, aud)}
+		}
+	}
+	if !found {
+		return &ClaimError{Claim: "aud",
+			Err: fmt.Errorf("%w: client %q not in audience", ErrBadClaim, v.ClientID)}
+	}
+
+	azp, ok := token.Claims["azp"]
+	if !ok && len(audience) > 1 {
+		return &ClaimError{Claim: "azp", Err: ErrMissingClaim}
+	}
+	if ok && azp != v.ClientID {
+		return &ClaimError{Claim: "azp", Err: fmt.Errorf("%w: %v", ErrBadClaim, azp)}
+	}
+	return nil
+}
+
+// checkAuthTime проверяет, что аутентификация была не раньше MaxAge.
+func (v *IDTokenVerifier) checkAuthTime(token *Token) error {
+	authTime := token.Time("auth_time")
+	if authTime.IsZero() {
+		return &ClaimError{Claim: "auth_time", Err: ErrMissingClaim}
+	}
+	now := time.Now()
+	if authTime.Add(v.MaxAge).Before(now) {
+		return &ClaimError{Claim: "auth_time", Time: authTime, Now: now,
+			Err: ErrAuthTooOld}
+	}
+	return nil
+}
+
+// AccessTokenHash возвращает проверку поля at_hash ID-токена для указанного
+// токена доступа. Если поле не задано, то проверка считается пройденной;
+// чтобы сделать его обязательным, добавьте RequireClaims("at_hash").
+func AccessTokenHash(accessToken string) Validator {
+	return halfHashValidator("at_hash", accessToken)
+}
+
+// CodeHash возвращает проверку поля c_hash ID-токена для указанного кода
+// авторизации. Если поле не задано, то проверка считается пройденной;
+// чтобы сделать его обязательным, добавьте RequireClaims("c_hash").
+func CodeHash(code string) Validator {
+	return halfHashValidator("c_hash", code)
+}
+
+// halfHashValidator возвращает проверку поля с половинным хешем значения.
+func halfHashValidator(claim, value string) Validator {
+	return func(token *Token) error {
+		expected, ok := token.Claims[claim]
+		if !ok {
+			return nil
+		}
+		hash, err := HalfHash(token.Header.Algorithm, value)
+		if err != nil {
+			return &ClaimError{Claim: claim, Err: err}
+		}
+		s, _ := expected.(string)
+		if subtle.ConstantTimeCompare([]byte(s), []byte(hash)) != 1 {
+			return &ClaimError{Claim: claim, Err: ErrBadClaim}
+		}
+		return nil
+	}
+}
+
+// HalfHash вычисляет значение для полей at_hash и c_hash ID-токена: левую
+// половину хеша значения, закодированную в base64. Хеш-функция определяется
+// алгоритмом подписи токена (OpenID Connect Core 1.0, раздел 3.1.3.6).
+func HalfHash(alg, value string) (string, error) {
+	var hash crypto.Hash
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		hash = crypto.SHA256
+	case strings.HasSuffix(alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		hash = crypto.SHA512
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if !hash.Available() {
+		return "", ErrBadHashFunc
+	}
+
+	h := hash.New()
+	_, _ = h.Write([]byte(value))
+	sum := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
+
+// HalfHash вычисляет значение для полей at_hash и c_hash ID-токена,
+// подписываемого ключом из шаблона.
+func (c Config) HalfHash(value string) (string, error) {
+	_, key := signingKey(c.Key)
+	alg, _ := algorithm(key)
+	if alg == "none" {
+		return "", ErrEmptySignKey
+	}
+	return HalfHash(alg, value)
+}
@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIDTokenVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := JWKEncode(&key.PublicKey, "rsa1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &Keys{Keys: []*JWK{jwk}}
+
+	issuer := Config{
+		Issuer:  "https://server.example.com",
+		Created: true,
+		Expires: time.Hour,
+		Key:     func() (string, interface{}) { return "rsa1", key },
+	}
+	atHash, err := issuer.HalfHash("access-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken := func(claims JSON) string {
+		t.Helper()
+		token, err := issuer.Token(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	verifier := &IDTokenVerifier{
+		Issuer:           "https://server.example.com",
+		ClientID:         "s6BhdRkqt3",
+		Key:              keys.Key,
+		TrustedAudiences: []string{"api"},
+		MaxAge:           time.Hour,
+		ACRValues:        []string{"urn:mace:incommon:iap:silver"},
+	}
+
+	token := idToken(JSON{
+		"sub":       "24400320",
+		"aud":       []string{"s6BhdRkqt3", "api"},
+		"azp":       "s6BhdRkqt3",
+		"nonce":     "n-0S6_WzA2Mj",
+		"auth_time": time.Now().Add(-time.Minute).Unix(),
+		"acr":       "urn:mace:incommon:iap:silver",
+		"at_hash":   atHash,
+	})
+	claims, err := verifier.Verify(token, "n-0S6_WzA2Mj", AccessTokenHash("access-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "24400320" || claims.Nonce != "n-0S6_WzA2Mj" ||
+		claims.AuthorizedParty != "s6BhdRkqt3" || claims.AuthTime.IsZero() ||
+		!claims.Audience.Contains("api") {
+		t.Errorf("bad claims: %+v", claims)
+	}
+
+	// неверные значения
+	for name, check := range map[string]struct {
+		claims JSON
+		nonce  string
+		err    error
+	}{
+		"nonce": {JSON{"sub": "1", "aud": "s6BhdRkqt3",
+			"auth_time": time.Now().Unix(), "acr": "urn:mace:incommon:iap:silver",
+			"nonce": "other"}, "n-0S6_WzA2Mj", ErrBadClaim},
+		"azp": {JSON{"sub": "1", "aud": []string{"s6BhdRkqt3", "api"},
+			"auth_time": time.Now().Unix(), "acr": "urn:mace:incommon:iap:silver"},
+			"", ErrMissingClaim},
+		"aud": {JSON{"sub": "1", "aud": []string{"s6BhdRkqt3", "other"},
+			"azp": "s6BhdRkqt3", "auth_time": time.Now().Unix(),
+			"acr": "urn:mace:incommon:iap:silver"}, "", ErrBadClaim},
+		"client": {JSON{"sub": "1", "aud": "api",
+			"auth_time": time.Now().Unix(), "acr": "urn:mace:incommon:iap:silver"},
+			"", ErrBadClaim},
+		"auth_time": {JSON{"sub": "1", "aud": "s6BhdRkqt3",
+			"auth_time": time.Now().Add(-2 * time.Hour).Unix(),
+			"acr":       "urn:mace:incommon:iap:silver"}, "", ErrAuthTooOld},
+		"max_age": {JSON{"sub": "1", "aud": "s6BhdRkqt3",
+			"acr": "urn:mace:incommon:iap:silver"}, "", ErrMissingClaim},
+		"acr": {JSON{"sub": "1", "aud": "s6BhdRkqt3",
+			"auth_time": time.Now().Unix(), "acr": "0"}, "", ErrBadClaim},
+		"at_hash": {JSON{"sub": "1", "aud": "s6BhdRkqt3",
+			"auth_time": time.Now().Unix(), "acr": "urn:mace:incommon:iap:silver",
+			"at_hash": "bad"}, "", ErrBadClaim},
+	} {
+		_, err := verifier.Verify(idToken(check.claims), check.nonce,
+			AccessTokenHash("access-token"))
+		var claimErr *ClaimError
+		if !errors.Is(err, check.err) || !errors.As(err, &claimErr) {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	// алгоритм подписи не разрешен
+	verifier.Algorithms = []string{"ES256"}
+	if _, err := verifier.Verify(token, ""); !errors.Is(err, ErrBadClaim) {
+		t.Error("unexpected algorithm accepted:", err)
+	}
+}
+
+func TestHalfHash(t *testing.T) {
+	hash, err := HalfHash("ES256", "access-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("access-token"))
+	if hash != base64.RawURLEncoding.EncodeToString(sum[:16]) {
+		t.Errorf("bad at_hash: %s", hash)
+	}
+	if hash, _ := HalfHash("RS512", "access-token"); len(hash) != 43 {
+		t.Errorf("bad at_hash length: %s", hash)
+	}
+	if _, err := HalfHash("none", "value"); err == nil {
+		t.Error("hash for unsupported algorithm")
+	}
+}
+
+func TestKeysLookup(t *testing.T) {
+	first, _ := JWKEncode(&NewES256Key().PublicKey, "first")
+	second, _ := JWKEncode(&NewES256Key().PublicKey, "second")
+	second.Usage = "enc"
+	keys := &Keys{Keys: []*JWK{first, second}}
+
+	if jwk, err := keys.Lookup("ES256", ""); err != nil || jwk != first {
+		t.Error("key not found:", err)
+	}
+	if _, err := keys.Lookup("ES256", "second"); err == nil {
+		t.Error("encryption key used for signature")
+	}
+	if _, ok := keys.Key("ES256", "unknown").(error); !ok {
+		t.Error("unknown key found")
+	}
+}
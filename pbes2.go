@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// PBES2Count задает количество итераций PBKDF2, используемое при
+// шифровании паролем, если оно не указано явно в Encryption.
+var PBES2Count = 310000
+
+// PBES2MaxCount ограничивает количество итераций (p2c), которое принимается
+// при расшифровке токена. Токены с большим значением отвергаются, чтобы
+// избежать исчерпания ресурсов процессора при разборе враждебных токенов.
+var PBES2MaxCount = 1000000
+
+// pbes2Params возвращает хеш-функцию и размер ключа для алгоритма PBES2.
+func pbes2Params(alg string) (func() hash.Hash, int, error) {
+	switch alg {
+	case "PBES2-HS256+A128KW":
+		return sha256.New, 16, nil
+	case "PBES2-HS384+A192KW":
+		return sha512.New384, 24, nil
+	case "PBES2-HS512+A256KW":
+		return sha512.New, 32, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported key management algorithm %q", alg)
+	}
+}
+
+// pbes2Encrypt формирует ключ шифрования содержимого и шифрует его ключом,
+// полученным из пароля. Соль и количество итераций сохраняются в заголовке.
+func pbes2Encrypt(header *jweHeader, password, salt []byte, count int) (cek, encryptedKey []byte, err error) {
+	h, size, err := pbes2Params(header.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	if count <= 0 {
+		count = PBES2Count
+	}
+	if salt == nil {
+		salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, nil, err
+		}
+	} else if len(salt) < 8 {
+		return nil, nil, fmt.Errorf("pbes2: salt must be at least 8 bytes")
+	}
+	header.Salt = base64.RawURLEncoding.EncodeToString(salt)
+	header.Count = count
+
+	kek := pbkdf2(h, password, pbes2Salt(header.Algorithm, salt), count, size)
+	if cek, err = newCEK(header.Encryption); err != nil {
+		return nil, nil, err
+	}
+	if encryptedKey, err = aesKeyWrap(kek, cek); err != nil {
+		return nil, nil, err
+	}
+	return cek, encryptedKey, nil
+}
+
+// pbes2Decrypt восстанавливает ключ шифрования содержимого по паролю.
+func pbes2Decrypt(header *jweHeader, password, encryptedKey []byte) ([]byte, error) {
+	h, size, err := pbes2Params(header.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if header.Count <= 0 || header.Count > PBES2MaxCount {
+		return nil, fmt.Errorf("pbes2: unacceptable iteration count %d", header.Count)
+	}
+	salt, err := decodeSegment(header.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(salt) < 8 {
+		return nil, fmt.Errorf("pbes2: salt must be at least 8 bytes")
+	}
+
+	kek := pbkdf2(h, password, pbes2Salt(header.Algorithm, salt), header.Count, size)
+	return aesKeyUnwrap(kek, encryptedKey)
+}
+
+// pbes2Salt возвращает соль для PBKDF2: название алгоритма, нулевой байт и
+// соль из заголовка.
+func pbes2Salt(alg string, salt []byte) []byte {
+	result := make([]byte, 0, len(alg)+1+len(salt))
+	result = append(result, alg...)
+	result = append(result, 0)
+	return append(result, salt...)
+}
+
+// pbkdf2 формирует ключ из пароля по алгоритму PBKDF2 (RFC 8018).
+func pbkdf2(h func() hash.Hash, password, salt []byte, iter, size int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	blocks := (size + hashLen - 1) / hashLen
+
+	var result []byte
+	buf := make([]byte, 4)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		_, _ = prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		_, _ = prf.Write(buf)
+		u = prf.Sum(u[:0])
+		t := make([]byte, hashLen)
+		copy(t, u)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			_, _ = prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		result = append(result, t...)
+	}
+
+	return result[:size]
+}
@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	for iter, expected := range map[int]string{
+		1:    "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b",
+		4096: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a",
+	} {
+		key := pbkdf2(sha256.New, []byte("password"), []byte("salt"), iter, 32)
+		if got := hex.EncodeToString(key); got != expected {
+			t.Errorf("bad pbkdf2 key for %d iterations: %s", iter, got)
+		}
+	}
+}
+
+func TestPBES2KeyUnwrap(t *testing.T) {
+	// RFC 7517, Appendix C
+	header := &jweHeader{
+		Algorithm:  "PBES2-HS256+A128KW",
+		Encryption: "A128CBC-HS256",
+		Salt:       "2WCTcJZ1Rvd_CJuJripQ1w",
+		Count:      4096,
+	}
+	encryptedKey, err := decodeSegment("TrqXOwuNUfDV9VPTNbyGvEJ9JMjefAVn-TR1uIxR9p6hsRQh9Tk7BA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cek, err := pbes2Decrypt(header,
+		[]byte("Thus from my lips, by yours, my sin is purged."), encryptedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{111, 27, 25, 52, 66, 29, 20, 78, 92, 176, 56, 240, 65,
+		208, 82, 112, 161, 131, 36, 55, 202, 236, 185, 172, 129, 23, 153, 194,
+		195, 48, 253, 182}
+	if !bytes.Equal(cek, expected) {
+		t.Errorf("bad content encryption key: %v", cek)
+	}
+}
+
+func TestEncryptPBES2(t *testing.T) {
+	const passphrase = "correct horse battery staple"
+	payload := []byte(`{"secret":"value"}`)
+
+	for _, alg := range []string{"PBES2-HS256+A128KW", "PBES2-HS384+A192KW", "PBES2-HS512+A256KW"} {
+		e := Encryption{
+			Algorithm: alg,
+			Salt:      []byte("0123456789abcdef"),
+			Count:     1000,
+			Key:       passphrase,
+		}
+		token, err := e.Encrypt(payload)
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		data, err := Decrypt(token, []byte(passphrase))
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		if !bytes.Equal(data, payload) {
+			t.Fatal(alg, "bad decrypted payload")
+		}
+		if _, err := Decrypt(token, "wrong passphrase"); err != ErrDecrypt {
+			t.Error(alg, "decrypted with wrong passphrase:", err)
+		}
+	}
+}
+
+func TestDecryptPBES2MaxCount(t *testing.T) {
+	defer func(count int) { PBES2MaxCount = count }(PBES2MaxCount)
+	PBES2MaxCount = 1000
+
+	e := Encryption{Count: PBES2MaxCount + 1, Key: "passphrase"}
+	token, err := e.Encrypt([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(token, "passphrase"); err == nil ||
+		!strings.Contains(err.Error(), "iteration count") {
+		t.Error("iteration count limit not enforced:", err)
+	}
+}
@@ -0,0 +1,267 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RefreshTokenType задает тип токена обновления в заголовке. Отдельный тип
+// не позволяет использовать токен обновления вместо токена доступа.
+const RefreshTokenType = "refresh+jwt"
+
+// refreshReservedClaims содержит поля, которые не переносятся из токена
+// обновления в новую пару токенов.
+var refreshReservedClaims = []string{"iss", "aud", "exp", "iat", "nbf", "jti", "typ", "fam", "ati"}
+
+// TokenPair описывает пару из токена доступа и токена обновления. Поля
+// соответствуют ответу сервера авторизации (RFC 6749, раздел 5.1).
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`            // токен доступа
+	TokenType    string `json:"token_type"`              // всегда "Bearer"
+	ExpiresIn    int64  `json:"expires_in,omitempty"`    // время жизни токена доступа в секундах
+	RefreshToken string `json:"refresh_token,omitempty"` // токен обновления
+}
+
+// RefreshStore описывает хранилище семейств токенов обновления. Семейство
+// образуют все токены обновления, полученные друг из друга при ротации;
+// действителен только последний из них.
+type RefreshStore interface {
+	// Start регистрирует новое семейство с токеном обновления id,
+	// действующим до expires.
+	Start(family, id string, expires time.Time) error
+	// Rotate атомарно заменяет текущий токен обновления семейства id на
+	// next. Если id не является текущим токеном семейства, то это означает
+	// повторное использование: семейство отзывается и возвращается ошибка
+	// ErrReplayed. Для отозванного или неизвестного семейства возвращается
+	// ErrRevoked.
+	Rotate(family, id, next string, expires time.Time) error
+	// Revoke отзывает семейство токенов.
+	Revoke(family string) error
+}
+
+// RefreshConfig описывает выпуск и ротацию пар токенов доступа и
+// обновления.
+//
+// Токены доступа формируются по шаблону Access, а токены обновления - по
+// шаблону Refresh с типом "refresh+jwt" в заголовке. Время жизни и
+// получателей (Private["aud"]) для них задают отдельно, а ключи подписи
+// Access.Key и Refresh.Key обязательны. Оба токена получают уникальные
+// идентификаторы (jti) и идентификатор семейства (fam), а токен обновления
+// дополнительно содержит идентификатор выданного вместе с ним токена доступа
+// (ati).
+//
+// Токен обновления проверяется ключом VerifyKey (по умолчанию Refresh.Key)
+// и дополнительными проверками Validators. При каждом обновлении выдается
+// новая пара токенов, а предыдущий токен обновления перестает действовать.
+// Повторное предъявление уже замененного токена обновления отзывает все
+// семейство.
+type RefreshConfig struct {
+	Access     Config       // шаблон токена доступа
+	Refresh    Config       // шаблон токена обновления
+	VerifyKey  interface{}  // ключ для проверки токенов обновления
+	Validators []Validator  // дополнительные проверки токена обновления
+	Store      RefreshStore // хранилище семейств токенов обновления
+}
+
+// Issue выпускает новую пару токенов с новым семейством. Данные claimset
+// добавляются в оба токена и переносятся в новые пары при обновлении.
+func (c *RefreshConfig) Issue(claimset interface{}) (*TokenPair, error) {
+	claims, err := Config{}.claims(claimset)
+	if err != nil {
+		return nil, err
+	}
+
+	family := Nonce(16)()
+	pair, refreshID, expires, err := c.issue(family, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Store.Start(family, refreshID, expires); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Rotate проверяет токен обновления и возвращает новую пару токенов того же
+// семейства. Если токен уже был заменен при предыдущем обновлении, то все
+// семейство отзывается и возвращается ошибка ErrReplayed.
+func (c *RefreshConfig) Rotate(refreshToken string) (*TokenPair, error) {
+	key := c.VerifyKey
+	if key == nil {
+		key = c.Refresh.Key
+	}
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+
+	token, err := parse(refreshToken, key, RefreshTokenType,
+		append([]Validator{RequireClaims("jti", "fam")}, c.Validators...))
+	if err != nil {
+		return nil, err
+	}
+	family, id := token.String("fam"), token.String("jti")
+
+	claims := make(JSON, len(token.Claims))
+next:
+	for name, value := range token.Claims {
+		for _, reserved := range refreshReservedClaims {
+			if name == reserved {
+				continue next
+			}
+		}
+		claims[name] = value
+	}
+
+	pair, refreshID, expires, err := c.issue(family, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Store.Rotate(family, id, refreshID, expires); err != nil {
+		return nil, &ValidationError{Kind: ClaimsInvalid,
+			Errors: []error{&ClaimError{Claim: "jti", Err: err}}}
+	}
+	return pair, nil
+}
+
+// issue формирует пару токенов указанного семейства и возвращает ее вместе
+// с идентификатором и временем окончания действия токена обновления.
+func (c *RefreshConfig) issue(family string, claims JSON) (_ *TokenPair, refreshID string, expires time.Time, err error) {
+	if c.Access.Key == nil || c.Refresh.Key == nil {
+		return nil, "", expires, ErrEmptySignKey
+	}
+	if c.Store == nil {
+		return nil, "", expires, errors.New("refresh store not specified")
+	}
+	if c.Refresh.Expires <= 0 {
+		return nil, "", expires, errors.New("refresh token lifetime not specified")
+	}
+
+	id := Nonce(16)
+	accessID, refreshID := id(), id()
+	with := func(extra JSON) JSON {
+		result := make(JSON, len(claims)+len(extra))
+		for name, value := range claims {
+			result[name] = value
+		}
+		for name, value := range extra {
+			result[name] = value
+		}
+		return result
+	}
+
+	access, err := c.Access.Token(with(JSON{"jti": accessID, "fam": family}))
+	if err != nil {
+		return nil, "", expires, err
+	}
+
+	expires = time.Now().Add(c.Refresh.Expires)
+	refreshClaims, err := c.Refresh.claims(with(JSON{
+		"jti": refreshID, "fam": family, "ati": accessID}))
+	if err != nil {
+		return nil, "", expires, err
+	}
+	refresh, err := c.Refresh.encode(refreshClaims, &jwsHeader{Type: RefreshTokenType})
+	if err != nil {
+		return nil, "", expires, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(c.Access.Expires / time.Second),
+		RefreshToken: refresh,
+	}, refreshID, expires, nil
+}
+
+// RefreshFamilies хранит семейства токенов обновления в памяти и реализует
+// RefreshStore. Он так же реализует RevocationChecker, поэтому с помощью
+// CheckRevocation можно отвергать токены доступа из отозванных семейств.
+// Неизвестные семейства, в том числе удаленные Prune, считаются отозванными,
+// поэтому время жизни токена доступа не должно превышать время жизни токена
+// обновления.
+//
+// Безопасен для одновременного использования.
+type RefreshFamilies struct {
+	mu       sync.Mutex
+	families map[string]*refreshFamily
+}
+
+// refreshFamily описывает состояние семейства токенов обновления.
+type refreshFamily struct {
+	current string    // идентификатор действующего токена обновления
+	expires time.Time // время окончания действия последнего токена
+	revoked bool      // семейство отозвано
+}
+
+// NewRefreshFamilies возвращает новое пустое хранилище семейств токенов
+// обновления.
+func NewRefreshFamilies() *RefreshFamilies {
+	return &RefreshFamilies{families: make(map[string]*refreshFamily)}
+}
+
+// Start регистрирует новое семейство токенов.
+func (s *RefreshFamilies) Start(family, id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.families[family]; ok {
+		return fmt.Errorf("refresh token family %q already exists", family)
+	}
+	s.families[family] = &refreshFamily{current: id, expires: expires}
+	return nil
+}
+
+// Rotate заменяет текущий токен семейства.
+func (s *RefreshFamilies) Rotate(family, id, next string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.families[family]
+	switch {
+	case !ok || f.revoked:
+		return ErrRevoked
+	case f.current != id:
+		f.revoked = true // повторное использование: отзываем семейство
+		return ErrReplayed
+	}
+	f.current, f.expires = next, expires
+	return nil
+}
+
+// Revoke отзывает семейство токенов.
+func (s *RefreshFamilies) Revoke(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.families[family]; ok {
+		f.revoked = true
+	}
+	return nil
+}
+
+// Revoked возвращает ErrRevoked, если токен относится к отозванному или
+// неизвестному семейству.
+func (s *RefreshFamilies) Revoked(token *Token) error {
+	family := token.String("fam")
+	if family == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.families[family]; !ok || f.revoked {
+		return &ClaimError{Claim: "fam", Err: ErrRevoked}
+	}
+	return nil
+}
+
+// Prune удаляет семейства, все токены обновления которых уже истекли. Токены
+// доступа из удаленных семейств после этого отвергаются.
+func (s *RefreshFamilies) Prune() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for family, f := range s.families {
+		if f.expires.Before(now) {
+			delete(s.families, family)
+		}
+	}
+}
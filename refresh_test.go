@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefreshConfig(t *testing.T) {
+	key := NewES256Key()
+	families := NewRefreshFamilies()
+	conf := &RefreshConfig{
+		Access: Config{
+			Issuer:  "https://as.example.com/",
+			Expires: 5 * time.Minute,
+			Private: JSON{"aud": "https://api.example.com/"},
+			Key:     key,
+		},
+		Refresh: Config{
+			Issuer:  "https://as.example.com/",
+			Expires: 30 * 24 * time.Hour,
+			Private: JSON{"aud": "https://as.example.com/token"},
+			Key:     key,
+		},
+		VerifyKey:  &key.PublicKey,
+		Validators: []Validator{HasAudience("https://as.example.com/token")},
+		Store:      families,
+	}
+
+	pair, err := conf.Issue(JSON{"sub": "user", "scope": "read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 300 {
+		t.Errorf("bad token pair: %+v", pair)
+	}
+
+	access, err := parse(pair.AccessToken, &key.PublicKey, "", []Validator{
+		HasAudience("https://api.example.com/"), CheckRevocation(families)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if access.String("sub") != "user" || access.String("fam") == "" {
+		t.Errorf("bad access token: %v", access.Claims)
+	}
+	// токен обновления не принимается вместо токена доступа
+	if _, err := Verify(pair.RefreshToken, &key.PublicKey); !errors.Is(err, ErrBadType) {
+		t.Error("refresh token accepted as access token:", err)
+	}
+
+	next, err := conf.Rotate(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := parse(next.AccessToken, &key.PublicKey, "", []Validator{CheckRevocation(families)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.String("sub") != "user" || refreshed.String("scope") != "read" ||
+		refreshed.String("fam") != access.String("fam") || refreshed.String("jti") == access.String("jti") {
+		t.Errorf("bad refreshed access token: %v", refreshed.Claims)
+	}
+
+	// повторное использование замененного токена отзывает семейство
+	if _, err := conf.Rotate(pair.RefreshToken); !errors.Is(err, ErrReplayed) {
+		t.Fatal("reused refresh token accepted:", err)
+	}
+	if _, err := conf.Rotate(next.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Error("refresh token of revoked family accepted:", err)
+	}
+	if _, err := Verify(next.AccessToken, &key.PublicKey, CheckRevocation(families)); !errors.Is(err, ErrRevoked) {
+		t.Error("access token of revoked family accepted:", err)
+	}
+
+	// другие семейства не затрагиваются
+	other, _ := conf.Issue(JSON{"sub": "other"})
+	if _, err := conf.Rotate(other.RefreshToken); err != nil {
+		t.Error(err)
+	}
+
+	// токен доступа не принимается вместо токена обновления
+	if _, err := conf.Rotate(other.AccessToken); !errors.Is(err, ErrBadType) {
+		t.Error("access token accepted as refresh token:", err)
+	}
+
+	// без ключей токены не выпускаются
+	unsigned := *conf
+	unsigned.Access.Key = nil
+	if _, err := unsigned.Issue(JSON{"sub": "user"}); err != ErrEmptySignKey {
+		t.Error("unsigned access token:", err)
+	}
+	unsigned = *conf
+	unsigned.Refresh.Key = nil
+	if _, err := unsigned.Issue(JSON{"sub": "user"}); err != ErrEmptySignKey {
+		t.Error("unsigned refresh token:", err)
+	}
+
+	// семейства, удаленные из хранилища, считаются отозванными
+	if err := families.Start("expired", "1", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	_ = families.Revoke("expired")
+	families.Prune()
+	if err := families.Revoked(&Token{Claims: JSON{"fam": "expired"}}); !errors.Is(err, ErrRevoked) {
+		t.Error("pruned revoked family accepted:", err)
+	}
+	if err := families.Revoked(&Token{Claims: JSON{"fam": "unknown"}}); !errors.Is(err, ErrRevoked) {
+		t.Error("unknown family accepted:", err)
+	}
+	if _, err := Verify(other.AccessToken, &key.PublicKey, CheckRevocation(families)); err != nil {
+		t.Error("access token of active family rejected:", err)
+	}
+}
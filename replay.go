@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ReplayStore описывает хранилище идентификаторов (jti) уже использованных
+// токенов.
+type ReplayStore interface {
+	// Seen проверяет, встречался ли уже идентификатор токена, и запоминает
+	// его до указанного времени. Если время не задано, то срок хранения
+	// определяет само хранилище. Проверка и запоминание должны выполняться
+	// атомарно.
+	Seen(id string, expires time.Time) (bool, error)
+}
+
+// PreventReplay возвращает проверку, которая отвергает повторное
+// использование токена с тем же идентификатором (jti). Токены без
+// идентификатора тоже отвергаются.
+//
+// Идентификатор запоминается в хранилище до окончания срока действия токена
+// (exp) и только если токен прошел все остальные проверки, включая проверку
+// времени действия, независимо от того, в каком порядке они указаны.
+func PreventReplay(store ReplayStore) Validator {
+	return preventReplay(store, func(token *Token) time.Time {
+		return token.Time("exp")
+	})
+}
+
+// preventReplay возвращает проверку повторного использования токена, для
+// которой срок хранения идентификатора определяет функция expires.
+func preventReplay(store ReplayStore, expires func(token *Token) time.Time) Validator {
+	return func(token *Token) error {
+		id := token.String("jti")
+		if id == "" {
+			return &ClaimError{Claim: "jti", Err: ErrMissingClaim}
+		}
+		return token.afterValidation(func() error {
+			seen, err := store.Seen(id, expires(token))
+			if err != nil {
+				return err
+			}
+			if seen {
+				return &ClaimError{Claim: "jti", Err: ErrReplayed}
+			}
+			return nil
+		})
+	}
+}
+
+// ReplayCache хранит идентификаторы использованных токенов в памяти.
+// Количество хранимых идентификаторов ограничено: при переполнении
+// вытесняются те, что дольше всего не встречались. Поэтому размер кеша
+// должен быть достаточным для всех токенов, действующих одновременно.
+//
+// Безопасен для одновременного использования.
+type ReplayCache struct {
+	size  int           // максимальное количество идентификаторов
+	ttl   time.Duration // срок хранения для токенов без exp
+	mu    sync.Mutex
+	list  *list.List               // идентификаторы в порядке использования
+	items map[string]*list.Element // идентификаторы для быстрого поиска
+}
+
+// replayEntry описывает запомненный идентификатор токена.
+type replayEntry struct {
+	id      string
+	expires time.Time
+}
+
+// NewReplayCache возвращает новый кеш идентификаторов токенов указанного
+// размера. Для токенов без времени окончания действия идентификатор
+// хранится ttl. Если ttl не задан, то такие идентификаторы хранятся, пока не
+// будут вытеснены.
+func NewReplayCache(size int, ttl time.Duration) *ReplayCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &ReplayCache{
+		size:  size,
+		ttl:   ttl,
+		list:  list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Seen проверяет, встречался ли уже идентификатор токена, и запоминает его.
+func (c *ReplayCache) Seen(id string, expires time.Time) (bool, error) {
+	now := time.Now()
+	if expires.IsZero() && c.ttl > 0 {
+		expires = now.Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[id]; ok {
+		entry := elem.Value.(*replayEntry)
+		if entry.expires.IsZero() || entry.expires.After(now) {
+			c.list.MoveToFront(elem)
+			return true, nil
+		}
+		// срок хранения истек: запоминаем заново
+		entry.expires = expires
+		c.list.MoveToFront(elem)
+		return false, nil
+	}
+
+	// удаляем устаревшие и вытесняем лишние идентификаторы
+	for elem := c.list.Back(); elem != nil; {
+		entry := elem.Value.(*replayEntry)
+		if c.list.Len() < c.size &&
+			(entry.expires.IsZero() || entry.expires.After(now)) {
+			break
+		}
+		prev := elem.Prev()
+		c.list.Remove(elem)
+		delete(c.items, entry.id)
+		elem = prev
+	}
+
+	c.items[id] = c.list.PushFront(&replayEntry{id: id, expires: expires})
+	return false, nil
+}
+
+// Len возвращает количество запомненных идентификаторов.
+func (c *ReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list.Len()
+}
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPreventReplay(t *testing.T) {
+	conf := Config{
+		Expires:  time.Hour,
+		UniqueID: Nonce(16),
+		Key:      "secret",
+	}
+	cache := NewReplayCache(100, 0)
+
+	token, err := conf.Token("password-reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(token, "secret", PreventReplay(cache)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(token, "secret", PreventReplay(cache)); !errors.Is(err, ErrReplayed) {
+		t.Error("replayed token accepted:", err)
+	}
+
+	// токены с неверной подписью не запоминаются
+	other, err := Config{Expires: time.Hour, UniqueID: Nonce(16), Key: "other"}.Token("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(other, "secret", PreventReplay(cache)); err == nil {
+		t.Fatal("bad signature accepted")
+	}
+	if cache.Len() != 1 {
+		t.Error("token with bad signature remembered")
+	}
+
+	// просроченные и отвергнутые другими проверками токены не запоминаются
+	expired, err := Config{UniqueID: Nonce(16), Key: "secret"}.Token(JSON{
+		"exp": time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(expired, "secret", PreventReplay(cache)); !errors.Is(err, ErrExpired) {
+		t.Fatal("expired token accepted:", err)
+	}
+	other, _ = conf.Token("x")
+	if _, err := Verify(other, "secret", IssuedBy("issuer"), PreventReplay(cache)); err == nil {
+		t.Fatal("token without issuer accepted")
+	}
+	if _, err := Verify(other, "secret", All(IssuedBy("issuer"), PreventReplay(cache))); err == nil {
+		t.Fatal("token without issuer accepted")
+	}
+	// порядок проверок не важен
+	if _, err := Verify(other, "secret", PreventReplay(cache), IssuedBy("issuer")); err == nil {
+		t.Fatal("token without issuer accepted")
+	}
+	if _, err := Verify(other, "secret", All(PreventReplay(cache)), IssuedBy("issuer")); err == nil {
+		t.Fatal("token without issuer accepted")
+	}
+	if cache.Len() != 1 {
+		t.Error("rejected token remembered")
+	}
+
+	noID, err := Config{Key: "secret"}.Token("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(noID, "secret", PreventReplay(cache)); !errors.Is(err, ErrMissingClaim) {
+		t.Error("token without jti accepted:", err)
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	cache := NewReplayCache(3, time.Hour)
+	for i := 0; i < 5; i++ {
+		if seen, _ := cache.Seen(fmt.Sprint(i), time.Time{}); seen {
+			t.Fatal("new id seen")
+		}
+	}
+	if cache.Len() != 3 {
+		t.Errorf("bad cache size: %d", cache.Len())
+	}
+	if seen, _ := cache.Seen("4", time.Time{}); !seen {
+		t.Error("recent id evicted")
+	}
+	if seen, _ := cache.Seen("0", time.Time{}); seen {
+		t.Error("old id not evicted")
+	}
+
+	// идентификаторы с истекшим сроком хранения забываются
+	if seen, _ := cache.Seen("expired", time.Now().Add(-time.Second)); seen {
+		t.Fatal("new id seen")
+	}
+	if seen, _ := cache.Seen("expired", time.Now().Add(time.Hour)); seen {
+		t.Error("expired id remembered")
+	}
+}
@@ -0,0 +1,191 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RequestObjectType задает тип объекта запроса авторизации в заголовке
+// (RFC 9101, раздел 10.8).
+const RequestObjectType = "oauth-authz-req+jwt"
+
+// requestObjectClaims содержит поля объекта запроса, которые не являются
+// параметрами запроса авторизации.
+var requestObjectClaims = []string{"iss", "aud", "exp", "iat", "nbf", "jti"}
+
+// RequestObject описывает параметры для формирования объекта запроса
+// авторизации (JWT-Secured Authorization Request, RFC 9101).
+//
+// Объект запроса подписывается ключом клиента Key, в качестве издателя
+// указывается идентификатор клиента, а в качестве получателя - идентификатор
+// сервера авторизации. Если задано Encryption, то подписанный объект
+// дополнительно шифруется для сервера авторизации.
+type RequestObject struct {
+	ClientID   string        // iss и client_id - идентификатор клиента
+	Audience   string        // aud - идентификатор сервера авторизации
+	Expires    time.Duration // exp - время жизни, по умолчанию 5 минут
+	Key        interface{}   // ключ для подписи в формате Config.Key
+	Encryption *Encryption   // параметры шифрования
+}
+
+// Token возвращает объект запроса с указанными параметрами запроса
+// авторизации. Параметры с одним значением представляются строкой, а с
+// несколькими - массивом строк. Параметры request и request_uri не
+// допускаются, а параметры с именами полей iss, aud, exp, iat, nbf и jti
+// отвергаются с ошибкой ErrBadClaim, чтобы не подменить сформированные
+// поля объекта запроса.
+func (o RequestObject) Token(params url.Values) (string, error) {
+	if o.Key == nil {
+		return "", ErrEmptySignKey
+	}
+	if o.ClientID == "" || o.Audience == "" {
+		return "", errors.New("client_id and audience required")
+	}
+
+	claims := make(JSON, len(params)+2)
+	for name, values := range params {
+		for _, reserved := range requestObjectClaims {
+			if name == reserved {
+				return "", fmt.Errorf("%w: parameter %q is a reserved claim", ErrBadClaim, name)
+			}
+		}
+		switch {
+		case name == "request" || name == "request_uri":
+			return "", fmt.Errorf("parameter %q not allowed in request object", name)
+		case len(values) == 1:
+			claims[name] = values[0]
+		case len(values) > 1:
+			claims[name] = values
+		}
+	}
+	claims["client_id"] = o.ClientID
+	claims["aud"] = o.Audience
+
+	expires := o.Expires
+	if expires <= 0 {
+		expires = 5 * time.Minute
+	}
+	conf := Config{
+		Issuer:     o.ClientID,
+		Created:    true,
+		Expires:    expires,
+		UniqueID:   Nonce(16),
+		Key:        o.Key,
+		Encryption: o.Encryption,
+	}
+	claims, err := conf.claims(claims)
+	if err != nil {
+		return "", err
+	}
+	return conf.encode(claims, &jwsHeader{Type: RequestObjectType})
+}
+
+// RequestObjectVerifier проверяет объекты запроса авторизации на стороне
+// сервера авторизации (RFC 9101, раздел 6).
+//
+// Ключи клиента возвращает функция Keys по его идентификатору. Проверяется,
+// что в заголовке указан тип "oauth-authz-req+jwt", объект подписан одним
+// из алгоритмов Algorithms (по умолчанию RS256, ES256, ES384 и ES512),
+// издатель (iss) и client_id совпадают с идентификатором клиента, а среди
+// получателей (aud) есть Issuer. Дополнительные проверки задаются в
+// Validators.
+//
+// Если задан DecryptKey, то принимаются и зашифрованные объекты запроса.
+type RequestObjectVerifier struct {
+	Issuer     string                               // идентификатор сервера авторизации
+	Keys       func(clientID string) (*Keys, error) // ключи клиента
+	Algorithms []string                             // допустимые алгоритмы подписи
+	Validators []Validator                          // дополнительные проверки
+	DecryptKey interface{}                          // ключ для расшифровки
+}
+
+// Verify проверяет объект запроса клиента и возвращает содержащиеся в нем
+// параметры запроса авторизации. Поля iss, aud, exp, iat, nbf и jti в
+// параметры не попадают. Строковые значения возвращаются как есть, массивы
+// строк - несколькими значениями, а остальные значения - в формате JSON.
+func (v *RequestObjectVerifier) Verify(request, clientID string) (url.Values, error) {
+	if v.Keys == nil {
+		return nil, ErrEmptySignKey
+	}
+	if clientID == "" {
+		return nil, errors.New("client_id required")
+	}
+
+	if v.DecryptKey != nil && strings.Count(request, ".") == 4 {
+		inner, err := decryptNested(request, v.DecryptKey)
+		if err != nil {
+			return nil, err
+		}
+		request = inner
+	}
+
+	keys, err := v.Keys(clientID)
+	if err != nil {
+		return nil, err
+	}
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256", "ES256", "ES384", "ES512"}
+	}
+
+	validators := append([]Validator{
+		AllowAlgorithms(algorithms...),
+		RequireClaims("iss", "aud", "client_id"),
+		IssuedBy(clientID),
+		ClaimEquals("client_id", clientID),
+		HasAudience(v.Issuer),
+	}, v.Validators...)
+	token, err := parse(request, keys.Key, RequestObjectType, validators)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(url.Values, len(token.Claims))
+next:
+	for name, value := range token.Claims {
+		for _, claim := range requestObjectClaims {
+			if name == claim {
+				continue next
+			}
+		}
+		if s, ok := value.(string); ok {
+			params.Set(name, s)
+			continue
+		}
+		if list, ok := value.([]interface{}); ok {
+			if values := claimAudience(list); len(values) == len(list) {
+				params[name] = values // массив строк
+				continue
+			}
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		params.Set(name, string(data))
+	}
+	return params, nil
+}
+
+// VerifyRequest проверяет объект запроса из параметра request запроса
+// авторизации и возвращает содержащиеся в нем параметры. Параметр client_id
+// должен быть указан и вне объекта запроса. Передача объекта запроса по
+// ссылке (request_uri) не поддерживается.
+func (v *RequestObjectVerifier) VerifyRequest(r *http.Request) (url.Values, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	if r.Form.Get("request_uri") != "" {
+		return nil, errors.New("request_uri not supported")
+	}
+	requests := r.Form["request"]
+	if len(requests) != 1 {
+		return nil, errors.New("single request parameter required")
+	}
+	return v.Verify(requests[0], r.Form.Get("client_id"))
+}
@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRequestObject(t *testing.T) {
+	clientKey, serverKey := NewES256Key(), NewES256Key()
+	jwk, _ := JWKEncode(&clientKey.PublicKey, "")
+	verifier := &RequestObjectVerifier{
+		Issuer: "https://server.example.com",
+		Keys: func(clientID string) (*Keys, error) {
+			if clientID != "s6BhdRkqt3" {
+				return nil, fmt.Errorf("unknown client %q", clientID)
+			}
+			return &Keys{Keys: []*JWK{jwk}}, nil
+		},
+		DecryptKey: serverKey,
+	}
+
+	params := url.Values{
+		"response_type": {"code"},
+		"redirect_uri":  {"https://client.example.org/cb"},
+		"scope":         {"openid"},
+		"state":         {"af0ifjsldkj"},
+		"acr_values":    {"urn:a", "urn:b"},
+	}
+	object := RequestObject{
+		ClientID: "s6BhdRkqt3",
+		Audience: "https://server.example.com",
+		Key:      clientKey,
+	}
+	encrypted := object
+	encrypted.Encryption = &Encryption{Key: &serverKey.PublicKey}
+
+	for name, object := range map[string]RequestObject{
+		"signed":    object,
+		"encrypted": encrypted,
+	} {
+		request, err := object.Token(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("GET", "/authorize?"+url.Values{
+			"client_id": {"s6BhdRkqt3"},
+			"request":   {request},
+		}.Encode(), nil)
+		result, err := verifier.VerifyRequest(r)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if result.Get("state") != "af0ifjsldkj" || result.Get("client_id") != "s6BhdRkqt3" ||
+			len(result["acr_values"]) != 2 || result.Get("iss") != "" || result.Get("exp") != "" {
+			t.Errorf("%s: bad params: %v", name, result)
+		}
+	}
+
+	request, _ := object.Token(params)
+	if _, err := verifier.Verify(request, "other"); err == nil {
+		t.Error("request object accepted for other client")
+	}
+	wrongAudience := object
+	wrongAudience.Audience = "https://other.example.com"
+	request, _ = wrongAudience.Token(params)
+	if _, err := verifier.Verify(request, "s6BhdRkqt3"); !errors.Is(err, ErrBadClaim) {
+		t.Error("wrong audience accepted:", err)
+	}
+	forged := object
+	forged.Key = NewES256Key()
+	request, _ = forged.Token(params)
+	if _, err := verifier.Verify(request, "s6BhdRkqt3"); !errors.Is(err, ErrBadSignature) {
+		t.Error("forged request object accepted:", err)
+	}
+
+	// объект запроса без явного типа
+	untyped, _ := Config{Issuer: "s6BhdRkqt3", Key: clientKey}.Token(JSON{
+		"aud": "https://server.example.com", "client_id": "s6BhdRkqt3"})
+	if _, err := verifier.Verify(untyped, "s6BhdRkqt3"); !errors.Is(err, ErrBadType) {
+		t.Error("untyped request object accepted:", err)
+	}
+
+	for _, name := range []string{"iss", "aud", "exp", "iat", "nbf", "jti"} {
+		if _, err := object.Token(url.Values{name: {"1"}}); !errors.Is(err, ErrBadClaim) {
+			t.Errorf("reserved parameter %s in request object: %v", name, err)
+		}
+	}
+	if _, err := object.Token(url.Values{"request_uri": {"urn:x"}}); err == nil ||
+		!strings.Contains(err.Error(), "request_uri") {
+		t.Error("request_uri in request object:", err)
+	}
+}
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RevocationChecker описывает источник сведений об отозванных токенах.
+type RevocationChecker interface {
+	// Revoked возвращает ошибку, если токен отозван. Для описания причины
+	// лучше возвращать *ClaimError с ошибкой ErrRevoked.
+	Revoked(token *Token) error
+}
+
+// CheckRevocation возвращает проверку, которая отвергает отозванные токены.
+func CheckRevocation(checker RevocationChecker) Validator {
+	return checker.Revoked
+}
+
+// RevocationList хранит в памяти список отозванных токенов. Токены можно
+// отзывать по их идентификатору (jti), по субъекту (sub) для всех токенов,
+// выпущенных до указанного времени, или по идентификатору ключа подписи
+// (kid).
+//
+// Список можно сохранить в файл и загрузить из него, в том числе во время
+// работы приложения: загрузка полностью заменяет текущее содержимое.
+//
+// Безопасен для одновременного использования.
+type RevocationList struct {
+	mu       sync.RWMutex
+	ids      map[string]time.Time // jti и время окончания действия токена
+	subjects map[string]time.Time // sub и время, до которого токены отозваны
+	keys     map[string]struct{}  // отозванные ключи
+}
+
+// NewRevocationList возвращает новый пустой список отозванных токенов.
+func NewRevocationList() *RevocationList {
+	return &RevocationList{
+		ids:      make(map[string]time.Time),
+		subjects: make(map[string]time.Time),
+		keys:     make(map[string]struct{}),
+	}
+}
+
+// RevokeID отзывает токен с указанным идентификатором. Время окончания
+// действия токена позволяет удалить запись, когда она станет не нужна. Если
+// оно не задано, то запись хранится постоянно.
+func (l *RevocationList) RevokeID(id string, expires time.Time) {
+	l.mu.Lock()
+	l.ids[id] = expires
+	l.mu.Unlock()
+}
+
+// RevokeSubject отзывает все токены субъекта, выпущенные (iat) до указанного
+// времени. Токены субъекта без времени выпуска тоже считаются отозванными.
+func (l *RevocationList) RevokeSubject(subject string, before time.Time) {
+	l.mu.Lock()
+	if before.After(l.subjects[subject]) {
+		l.subjects[subject] = before
+	}
+	l.mu.Unlock()
+}
+
+// RevokeKey отзывает все токены, подписанные ключом с указанным
+// идентификатором.
+func (l *RevocationList) RevokeKey(keyID string) {
+	l.mu.Lock()
+	l.keys[keyID] = struct{}{}
+	l.mu.Unlock()
+}
+
+// Revoked возвращает ошибку, если токен отозван.
+func (l *RevocationList) Revoked(token *Token) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if id := token.String("jti"); id != "" {
+		if _, ok := l.ids[id]; ok {
+			return &ClaimError{Claim: "jti", Err: ErrRevoked}
+		}
+	}
+	if subject := token.String("sub"); subject != "" {
+		if before, ok := l.subjects[subject]; ok {
+			issued := token.Time("iat")
+			if issued.IsZero() || issued.Before(before) {
+				return &ClaimError{Claim: "sub", Time: issued, Now: before, Err: ErrRevoked}
+			}
+		}
+	}
+	if keyID := token.Header.KeyID; keyID != "" {
+		if _, ok := l.keys[keyID]; ok {
+			return &ClaimError{Claim: "kid", Err: ErrRevoked}
+		}
+	}
+	return nil
+}
+
+// Prune удаляет записи об отозванных токенах, срок действия которых истек.
+func (l *RevocationList) Prune() {
+	now := time.Now()
+	l.mu.Lock()
+	for id, expires := range l.ids {
+		if !expires.IsZero() && expires.Before(now) {
+			delete(l.ids, id)
+		}
+	}
+	l.mu.Unlock()
+}
+
+// revocationSnapshot описывает формат сохраненного списка отозванных
+// токенов. Время окончания действия представлено в числовом виде; 0
+// означает, что время не задано. Время отзыва по субъекту сохраняется в
+// формате RFC 3339 с наносекундами, так как токены, выпущенные в ту же
+// секунду, но после отзыва, должны оставаться действительными.
+type revocationSnapshot struct {
+	IDs      map[string]int64     `json:"jti,omitempty"` // jti и время окончания действия
+	Subjects map[string]time.Time `json:"sub,omitempty"` // sub и время отзыва
+	Keys     []string             `json:"kid,omitempty"` // отозванные ключи
+}
+
+// Save сохраняет список отозванных токенов в формате JSON. Записи об
+// истекших токенах не сохраняются.
+func (l *RevocationList) Save(w io.Writer) error {
+	l.Prune()
+
+	l.mu.RLock()
+	snapshot := revocationSnapshot{
+		IDs:      make(map[string]int64, len(l.ids)),
+		Subjects: make(map[string]time.Time, len(l.subjects)),
+		Keys:     make([]string, 0, len(l.keys)),
+	}
+	for id, expires := range l.ids {
+		if expires.IsZero() {
+			snapshot.IDs[id] = 0
+		} else {
+			snapshot.IDs[id] = expires.Unix()
+		}
+	}
+	for subject, before := range l.subjects {
+		snapshot.Subjects[subject] = before
+	}
+	for keyID := range l.keys {
+		snapshot.Keys = append(snapshot.Keys, keyID)
+	}
+	l.mu.RUnlock()
+	sort.Strings(snapshot.Keys)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(snapshot)
+}
+
+// Load загружает список отозванных токенов, сохраненный с помощью Save, и
+// заменяет им текущее содержимое.
+func (l *RevocationList) Load(r io.Reader) error {
+	var snapshot revocationSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	ids := make(map[string]time.Time, len(snapshot.IDs))
+	for id, expires := range snapshot.IDs {
+		if expires == 0 {
+			ids[id] = time.Time{}
+		} else {
+			ids[id] = time.Unix(expires, 0)
+		}
+	}
+	subjects := snapshot.Subjects
+	if subjects == nil {
+		subjects = make(map[string]time.Time)
+	}
+	keys := make(map[string]struct{}, len(snapshot.Keys))
+	for _, keyID := range snapshot.Keys {
+		keys[keyID] = struct{}{}
+	}
+
+	l.mu.Lock()
+	l.ids, l.subjects, l.keys = ids, subjects, keys
+	l.mu.Unlock()
+	return nil
+}
+
+// SaveFile сохраняет список отозванных токенов в файл. Файл заменяется
+// атомарно, поэтому его можно одновременно загружать в других процессах.
+func (l *RevocationList) SaveFile(name string) error {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := l.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+// LoadFile загружает список отозванных токенов из файла и заменяет им
+// текущее содержимое. Может вызываться повторно для обновления списка без
+// перезапуска приложения.
+func (l *RevocationList) LoadFile(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return l.Load(file)
+}
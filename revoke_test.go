@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRevocationList(t *testing.T) {
+	key := NewES256Key()
+	conf := Config{
+		Created:  true,
+		Expires:  time.Hour,
+		UniqueID: Nonce(16),
+		Key: func() (string, interface{}) {
+			return "key-1", key
+		},
+	}
+	newToken := func(sub string) string {
+		token, err := conf.Token(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	revoked := func(list *RevocationList, token string) bool {
+		_, err := Verify(token, key, CheckRevocation(list))
+		if err != nil && !errors.Is(err, ErrRevoked) {
+			t.Fatal(err)
+		}
+		return err != nil
+	}
+
+	list := NewRevocationList()
+	first, second := newToken("alice"), newToken("bob")
+	if revoked(list, first) || revoked(list, second) {
+		t.Fatal("token revoked by empty list")
+	}
+
+	parsed, err := parse(first, key, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list.RevokeID(parsed.String("jti"), parsed.Time("exp"))
+	if !revoked(list, first) || revoked(list, second) {
+		t.Error("bad revocation by jti")
+	}
+
+	list.RevokeSubject("bob", time.Now())
+	if !revoked(list, second) {
+		t.Error("bad revocation by subject")
+	}
+	conf.Created = false // токены без iat субъекта тоже отозваны
+	if !revoked(list, newToken("bob")) || revoked(list, newToken("carol")) {
+		t.Error("bad revocation by subject without iat")
+	}
+
+	// сохраняем и загружаем список
+	name := filepath.Join(t.TempDir(), "revoked.json")
+	if err := list.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	list.RevokeKey("key-1")
+	if !revoked(list, newToken("carol")) {
+		t.Error("bad revocation by key")
+	}
+	if err := list.LoadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked(list, first) || !revoked(list, second) || revoked(list, newToken("carol")) {
+		t.Error("bad reloaded revocation list")
+	}
+
+	// время отзыва по субъекту сохраняется с точностью до долей секунды
+	cutoff := time.Now().Add(-time.Minute).Truncate(time.Second).Add(500 * time.Millisecond)
+	issued := func(offset time.Duration) string {
+		token, err := conf.Token(JSON{"sub": "dave",
+			"iat": float64(cutoff.Add(offset).UnixNano()) / float64(time.Second)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	before, after := issued(-200*time.Millisecond), issued(200*time.Millisecond)
+	list.RevokeSubject("dave", cutoff)
+	if err := list.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewRevocationList()
+	if err := reloaded.LoadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked(reloaded, before) || revoked(reloaded, after) {
+		t.Error("subject cutoff lost sub-second precision")
+	}
+}
@@ -0,0 +1,442 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sdReservedClaims содержит поля, которые не могут раскрываться выборочно:
+// без них получатель не сможет проверить издателя, время действия токена и
+// ключ владельца.
+var sdReservedClaims = []string{"iss", "exp", "nbf", "iat", "cnf", "_sd", "_sd_alg"}
+
+// Типы токенов SD-JWT в заголовке.
+const (
+	SDJWTType      = "sd+jwt" // токен издателя
+	KeyBindingType = "kb+jwt" // токен привязки к ключу владельца
+)
+
+// SDJWTConfig описывает шаблон для выпуска токенов с выборочным раскрытием
+// полей (Selective Disclosure JWT).
+//
+// Токен формируется так же, как это делает Config, после чего поля,
+// перечисленные в Disclosable, заменяются хешами с солью в поле "_sd", а их
+// значения выносятся в отдельные раскрытия (disclosures). Поля iss, exp, nbf,
+// iat и cnf выборочно раскрывать нельзя. Для соли используется Nonce. Чтобы скрыть количество полей, можно добавить Decoys
+// ложных хешей.
+//
+// Если задан HolderKey, то в токен добавляется открытый ключ владельца
+// (cnf.jwk), и при предъявлении токена владелец должен подтвердить владение
+// ключом с помощью KeyBinding.
+//
+// Шифрование токена (Encryption) для SD-JWT не поддерживается.
+type SDJWTConfig struct {
+	Config
+	Disclosable []string    // поля с выборочным раскрытием
+	Decoys      int         // количество ложных хешей
+	HolderKey   interface{} // открытый ключ владельца
+}
+
+// Token возвращает токен SD-JWT со всеми раскрытиями: подписанный токен
+// издателя, за которым через "~" следуют раскрытия полей.
+func (c SDJWTConfig) Token(claimset interface{}) (string, error) {
+	if c.Key == nil {
+		return "", ErrEmptySignKey
+	}
+
+	claims, err := c.claims(claimset)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := claims["_sd"]; ok {
+		return "", fmt.Errorf("%w: reserved claim _sd", ErrBadClaim)
+	}
+
+	for _, name := range c.Disclosable {
+		for _, reserved := range sdReservedClaims {
+			if name == reserved {
+				return "", fmt.Errorf("%w: claim %s can not be disclosable", ErrBadClaim, name)
+			}
+		}
+	}
+
+	salt := Nonce(22) // 128 бит случайных данных
+	disclosures := make([]string, 0, len(c.Disclosable))
+	digests := make([]string, 0, len(c.Disclosable)+c.Decoys)
+	for _, name := range c.Disclosable {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		disclosure, err := json.Marshal([]interface{}{salt(), name, value})
+		if err != nil {
+			return "", err
+		}
+		encoded := base64.RawURLEncoding.EncodeToString(disclosure)
+		disclosures = append(disclosures, encoded)
+		digests = append(digests, sha256Base64(encoded))
+		delete(claims, name)
+	}
+	for i := 0; i < c.Decoys; i++ {
+		digests = append(digests, sha256Base64(salt()))
+	}
+	// порядок хешей не должен выдавать порядок полей
+	sort.Strings(digests)
+	claims["_sd"] = digests
+	claims["_sd_alg"] = "sha-256"
+
+	if c.HolderKey != nil {
+		jwk, err := JWKEncode(c.HolderKey, "")
+		if err != nil {
+			return "", err
+		}
+		if jwk.D != "" {
+			return "", errors.New("holder key must be public")
+		}
+		jwk.Usage, jwk.Algorithm = "", ""
+		claims["cnf"] = JSON{"jwk": jwk}
+	}
+
+	token, err := encode(claims, c.Key, &jwsHeader{Type: SDJWTType})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append([]string{token}, disclosures...), "~") + "~", nil
+}
+
+// SDJWTPresent возвращает предъявление токена SD-JWT, в котором оставлены
+// только раскрытия указанных полей. Остальные поля для получателя останутся
+// скрытыми. Раскрытия элементов массивов в предъявление не включаются.
+func SDJWTPresent(token string, names ...string) (string, error) {
+	parts := strings.Split(token, "~")
+	if len(parts) < 2 || parts[len(parts)-1] != "" {
+		return "", ErrInvalid
+	}
+
+	result := []string{parts[0]}
+	for _, encoded := range parts[1 : len(parts)-1] {
+		disclosure, err := decodeDisclosure(encoded)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range names {
+			if disclosure.name == name && !disclosure.element {
+				result = append(result, encoded)
+				break
+			}
+		}
+	}
+	return strings.Join(result, "~") + "~", nil
+}
+
+// KeyBinding описывает подтверждение владения ключом при предъявлении
+// токена SD-JWT (KB-JWT). Key задает закрытый ключ владельца, открытая часть
+// которого указана в токене издателя, а Audience и Nonce - получателя
+// предъявления и полученное от него случайное значение.
+type KeyBinding struct {
+	Audience string      // aud - получатель предъявления
+	Nonce    string      // nonce - значение, полученное от получателя
+	Key      interface{} // закрытый ключ владельца
+}
+
+// Bind добавляет к предъявлению токена SD-JWT подтверждение владения
+// ключом. Подтверждение содержит хеш предъявления (sd_hash), поэтому набор
+// раскрытий после этого изменить нельзя.
+func (b KeyBinding) Bind(presentation string) (string, error) {
+	if !strings.HasSuffix(presentation, "~") {
+		return "", ErrInvalid
+	}
+	if b.Key == nil {
+		return "", ErrEmptySignKey
+	}
+
+	kb, err := encode(JSON{
+		"iat":     time.Now().Unix(),
+		"aud":     b.Audience,
+		"nonce":   b.Nonce,
+		"sd_hash": sha256Base64(presentation),
+	}, b.Key, &jwsHeader{Type: KeyBindingType})
+	if err != nil {
+		return "", err
+	}
+	return presentation + kb, nil
+}
+
+// SDJWTVerifier проверяет предъявления токенов SD-JWT.
+//
+// Подпись токена издателя проверяется ключом Key, после чего поля из
+// раскрытий подставляются в содержимое токена, повторно проверяется время
+// его действия, и к нему применяются проверки Validators. Каждое раскрытие должно соответствовать хешу в
+// токене издателя.
+//
+// Если задан RequireKeyBinding или предъявление содержит подтверждение
+// владения ключом, то оно проверяется открытым ключом из cnf.jwk токена
+// издателя: получатель должен совпадать с Audience, значение nonce - с
+// Nonce, а время создания должно быть не раньше MaxAge (по умолчанию
+// 5 минут).
+type SDJWTVerifier struct {
+	Key               interface{}   // ключ издателя или функция его возвращающая
+	Validators        []Validator   // проверки содержимого токена
+	RequireKeyBinding bool          // требовать подтверждение владения ключом
+	Audience          string        // ожидаемый получатель предъявления
+	Nonce             string        // ожидаемое значение nonce
+	MaxAge            time.Duration // максимальное время с момента предъявления
+}
+
+// Verify проверяет предъявление и возвращает токен с раскрытыми полями.
+// Служебные поля "_sd" и "_sd_alg" из содержимого удаляются.
+func (v *SDJWTVerifier) Verify(presentation string) (*Token, error) {
+	if v.Key == nil {
+		return nil, ErrEmptySignKey
+	}
+
+	parts := strings.Split(presentation, "~")
+	if len(parts) < 2 {
+		return nil, ErrInvalid
+	}
+	keyBinding := parts[len(parts)-1]
+
+	token, err := parse(parts[0], v.Key, SDJWTType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// подставляем раскрытые поля
+	verr := new(ValidationError)
+	if alg, ok := token.Claims["_sd_alg"]; ok && alg != "sha-256" {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "_sd_alg",
+			Err: fmt.Errorf("%w: %v", ErrBadClaim, alg)})
+		return nil, verr
+	}
+	disclosures := make(map[string]*sdDisclosure, len(parts)-2)
+	for _, encoded := range parts[1 : len(parts)-1] {
+		disclosure, err := decodeDisclosure(encoded)
+		if err != nil {
+			verr.add(ClaimsInvalid, err)
+			return nil, verr
+		}
+		digest := sha256Base64(encoded)
+		if disclosures[digest] != nil {
+			verr.add(ClaimsInvalid, fmt.Errorf("%w: duplicate disclosure", ErrDisclosure))
+			return nil, verr
+		}
+		disclosures[digest] = disclosure
+	}
+	claims, err := expandDisclosures(token.Claims, disclosures)
+	if err != nil {
+		verr.add(ClaimsInvalid, err)
+		return nil, verr
+	}
+	for _, disclosure := range disclosures {
+		if !disclosure.used {
+			verr.add(ClaimsInvalid, fmt.Errorf("%w: unreferenced disclosure", ErrDisclosure))
+			return nil, verr
+		}
+	}
+	token.Claims = claims.(JSON)
+	delete(token.Claims, "_sd_alg")
+	if token.Raw, err = json.Marshal(token.Claims); err != nil {
+		return nil, err
+	}
+
+	// время действия могло быть в раскрытиях, поэтому проверяем его снова
+	for _, name := range []string{"iat", "exp", "nbf"} {
+		if value, ok := token.Claims[name]; ok {
+			if _, ok := value.(float64); !ok {
+				verr.add(ClaimsInvalid, &ClaimError{Claim: name,
+					Err: fmt.Errorf("%w: not a number", ErrBadClaim)})
+			}
+		}
+	}
+	checkTimes(verr, token.Time("iat"), token.Time("exp"), token.Time("nbf"))
+
+	// проверяем подтверждение владения ключом
+	if keyBinding != "" || v.RequireKeyBinding {
+		if keyBinding == "" {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: "kb+jwt", Err: ErrMissingClaim})
+		} else {
+			verr.merge(v.verifyKeyBinding(token,
+				presentation[:len(presentation)-len(keyBinding)], keyBinding))
+		}
+	}
+
+	token.validate(verr, v.Validators)
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return token, nil
+}
+
+// verifyKeyBinding проверяет подтверждение владения ключом.
+func (v *SDJWTVerifier) verifyKeyBinding(token *Token, presentation, keyBinding string) error {
+	// открытый ключ владельца берется из токена издателя
+	cnf, _ := token.Claims["cnf"].(JSON)
+	data, err := json.Marshal(cnf["jwk"])
+	if err != nil || cnf["jwk"] == nil {
+		return &ClaimError{Claim: "cnf", Err: ErrMissingClaim}
+	}
+	jwk := new(JWK)
+	if err := json.Unmarshal(data, jwk); err != nil {
+		return &ClaimError{Claim: "cnf", Err: fmt.Errorf("%w: %v", ErrBadClaim, err)}
+	}
+	key, err := jwk.Decode()
+	if err != nil {
+		return &ClaimError{Claim: "cnf", Err: fmt.Errorf("%w: %v", ErrBadClaim, err)}
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return &ClaimError{Claim: "cnf", Err: fmt.Errorf("%w: not a public key", ErrBadClaim)}
+	}
+
+	maxAge := v.MaxAge
+	if maxAge <= 0 {
+		maxAge = 5 * time.Minute
+	}
+	sdHash := sha256Base64(presentation)
+	_, err = parse(keyBinding, key, KeyBindingType, []Validator{
+		RequireClaims("iat", "aud", "nonce", "sd_hash"),
+		ClaimEquals("aud", v.Audience),
+		ClaimEquals("nonce", v.Nonce),
+		CheckClaim("sd_hash", func(value interface{}) error {
+			s, _ := value.(string)
+			if subtle.ConstantTimeCompare([]byte(s), []byte(sdHash)) != 1 {
+				return ErrBadClaim
+			}
+			return nil
+		}),
+		func(token *Token) error {
+			created, now := token.Time("iat"), time.Now()
+			if !created.IsZero() && created.Add(maxAge).Before(now) {
+				return &ClaimError{Claim: "iat", Time: created, Now: now, Err: ErrExpired}
+			}
+			return nil
+		},
+	})
+	return err
+}
+
+// sdDisclosure описывает раскрытие поля или элемента массива.
+type sdDisclosure struct {
+	name    string      // имя поля
+	value   interface{} // значение
+	element bool        // раскрытие элемента массива
+	used    bool        // раскрытие уже подставлено
+}
+
+// decodeDisclosure разбирает раскрытие поля или элемента массива.
+func decodeDisclosure(encoded string) (*sdDisclosure, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDisclosure, err)
+	}
+	var items []interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDisclosure, err)
+	}
+	if len(items) < 2 || len(items) > 3 {
+		return nil, fmt.Errorf("%w: bad length", ErrDisclosure)
+	}
+	if _, ok := items[0].(string); !ok {
+		return nil, fmt.Errorf("%w: bad salt", ErrDisclosure)
+	}
+	if len(items) == 2 {
+		return &sdDisclosure{value: items[1], element: true}, nil
+	}
+	name, ok := items[1].(string)
+	if !ok || name == "_sd" || name == "..." {
+		return nil, fmt.Errorf("%w: bad claim name", ErrDisclosure)
+	}
+	return &sdDisclosure{name: name, value: items[2]}, nil
+}
+
+// expandDisclosures подставляет раскрытые поля и элементы массивов вместо
+// их хешей на любом уровне вложенности. Хеши без раскрытий удаляются.
+func expandDisclosures(value interface{}, disclosures map[string]*sdDisclosure) (interface{}, error) {
+	lookup := func(digest interface{}, element bool) (*sdDisclosure, error) {
+		s, ok := digest.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: bad digest", ErrDisclosure)
+		}
+		disclosure := disclosures[s]
+		if disclosure == nil {
+			return nil, nil // ложный хеш или нераскрытое поле
+		}
+		if disclosure.used || disclosure.element != element {
+			return nil, fmt.Errorf("%w: digest reused", ErrDisclosure)
+		}
+		disclosure.used = true
+		return disclosure, nil
+	}
+
+	switch value := value.(type) {
+	case JSON:
+		result := make(JSON, len(value))
+		for name, item := range value {
+			if name == "_sd" {
+				continue
+			}
+			item, err := expandDisclosures(item, disclosures)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = item
+		}
+		if digests, ok := value["_sd"]; ok {
+			list, ok := digests.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: _sd is not an array", ErrDisclosure)
+			}
+			for _, digest := range list {
+				disclosure, err := lookup(digest, false)
+				if err != nil {
+					return nil, err
+				}
+				if disclosure == nil {
+					continue
+				}
+				if _, ok := result[disclosure.name]; ok {
+					return nil, fmt.Errorf("%w: claim %q already exists", ErrDisclosure, disclosure.name)
+				}
+				item, err := expandDisclosures(disclosure.value, disclosures)
+				if err != nil {
+					return nil, err
+				}
+				result[disclosure.name] = item
+			}
+		}
+		return result, nil
+
+	case []interface{}:
+		result := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if obj, ok := item.(JSON); ok && len(obj) == 1 && obj["..."] != nil {
+				disclosure, err := lookup(obj["..."], true)
+				if err != nil {
+					return nil, err
+				}
+				if disclosure == nil {
+					continue
+				}
+				item = disclosure.value
+			}
+			item, err := expandDisclosures(item, disclosures)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, item)
+		}
+		return result, nil
+
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,184 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSDJWT(t *testing.T) {
+	issuerKey, holderKey := NewES256Key(), NewES256Key()
+	issuer := SDJWTConfig{
+		Config: Config{
+			Issuer:  "https://issuer.example.com",
+			Created: true,
+			Expires: time.Hour,
+			Key:     issuerKey,
+		},
+		Disclosable: []string{"given_name", "family_name", "email", "birthdate"},
+		Decoys:      2,
+		HolderKey:   &holderKey.PublicKey,
+	}
+	token, err := issuer.Token(JSON{
+		"sub":         "user_42",
+		"given_name":  "John",
+		"family_name": "Doe",
+		"email":       "johndoe@example.com",
+		"birthdate":   "1940-01-01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Split(token, "~"); len(parts) != 6 {
+		t.Fatalf("bad disclosures count: %d", len(parts)-2)
+	}
+
+	// скрытые поля отсутствуют в токене издателя
+	payload, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if strings.Contains(string(payload), "John") {
+		t.Errorf("disclosable claim in payload: %s", payload)
+	}
+
+	presentation, err := SDJWTPresent(token, "given_name", "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	presentation, err = KeyBinding{
+		Audience: "https://verifier.example.org",
+		Nonce:    "1234567890",
+		Key:      holderKey,
+	}.Bind(presentation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := &SDJWTVerifier{
+		Key:               &issuerKey.PublicKey,
+		Validators:        []Validator{IssuedBy("https://issuer.example.com")},
+		RequireKeyBinding: true,
+		Audience:          "https://verifier.example.org",
+		Nonce:             "1234567890",
+	}
+	verified, err := verifier.Verify(presentation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := verified.Claims
+	if claims["given_name"] != "John" || claims["email"] != "johndoe@example.com" ||
+		claims["sub"] != "user_42" {
+		t.Errorf("bad claims: %v", claims)
+	}
+	for _, name := range []string{"family_name", "birthdate", "_sd", "_sd_alg"} {
+		if _, ok := claims[name]; ok {
+			t.Errorf("unexpected claim %q", name)
+		}
+	}
+
+	// подтверждение привязано к набору раскрытий
+	parts := strings.Split(presentation, "~")
+	tampered, _ := SDJWTPresent(token, "given_name", "email", "birthdate")
+	tampered += parts[len(parts)-1]
+	if _, err := verifier.Verify(tampered); !errors.Is(err, ErrBadClaim) {
+		t.Error("tampered presentation accepted:", err)
+	}
+
+	// подтверждение владения ключом обязательно
+	withoutKB, _ := SDJWTPresent(token, "email")
+	if _, err := verifier.Verify(withoutKB); !errors.Is(err, ErrMissingClaim) {
+		t.Error("presentation without key binding accepted:", err)
+	}
+	forgedKB, _ := KeyBinding{Audience: "https://verifier.example.org",
+		Nonce: "1234567890", Key: NewES256Key()}.Bind(withoutKB)
+	if _, err := verifier.Verify(forgedKB); !errors.Is(err, ErrBadSignature) {
+		t.Error("forged key binding accepted:", err)
+	}
+	wrongNonce, _ := KeyBinding{Audience: "https://verifier.example.org",
+		Nonce: "other", Key: holderKey}.Bind(withoutKB)
+	if _, err := verifier.Verify(wrongNonce); !errors.Is(err, ErrBadClaim) {
+		t.Error("wrong nonce accepted:", err)
+	}
+
+	// чужое раскрытие
+	other, _ := issuer.Token(JSON{"email": "other@example.com"})
+	foreign := strings.Split(token, "~")[0] + "~" + strings.Split(other, "~")[1] + "~"
+	verifier.RequireKeyBinding = false
+	if _, err := verifier.Verify(foreign); !errors.Is(err, ErrDisclosure) {
+		t.Error("foreign disclosure accepted:", err)
+	}
+	if _, err := verifier.Verify(token); err != nil {
+		t.Error(err)
+	}
+
+	// время действия нельзя скрыть, а раскрытое время проверяется
+	issuer.Disclosable = []string{"exp"}
+	if _, err := issuer.Token(JSON{"sub": "user_42"}); !errors.Is(err, ErrBadClaim) {
+		t.Error("disclosable exp accepted:", err)
+	}
+	expired := base64.RawURLEncoding.EncodeToString([]byte(
+		`["salt","exp",` + strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + `]`))
+	signed, err := encode(JSON{"iss": "https://issuer.example.com",
+		"_sd": []string{sha256Base64(expired)}, "_sd_alg": "sha-256"},
+		issuerKey, &jwsHeader{Type: SDJWTType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(signed + "~" + expired + "~"); !errors.Is(err, ErrExpired) {
+		t.Error("disclosed expired exp accepted:", err)
+	}
+}
+
+func TestSDJWTDisclosures(t *testing.T) {
+	// SD-JWT, пример раскрытия поля family_name
+	const disclosure = "WyI2cU1RdlJMNWhhaiIsICJmYW1pbHlfbmFtZSIsICJNw7ZiaXVzIl0"
+	if digest := sha256Base64(disclosure); digest != "uutlBuYeMDyjLLTpf6Jxi7yNkEF35jdyWMn9U7b_RYY" {
+		t.Errorf("bad digest: %s", digest)
+	}
+
+	// раскрытия вложенных полей и элементов массива
+	field := base64.RawURLEncoding.EncodeToString([]byte(`["salt1","country","DE"]`))
+	element := base64.RawURLEncoding.EncodeToString([]byte(`["salt2","FR"]`))
+	disclosures := make(map[string]*sdDisclosure)
+	for _, encoded := range []string{field, element} {
+		d, err := decodeDisclosure(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		disclosures[sha256Base64(encoded)] = d
+	}
+	claims := JSON{
+		"address": JSON{
+			"_sd":      []interface{}{sha256Base64(field), sha256Base64("decoy")},
+			"locality": "Berlin",
+		},
+		"nationalities": []interface{}{
+			JSON{"...": sha256Base64(element)},
+			JSON{"...": sha256Base64("hidden")},
+			"DE",
+		},
+	}
+	result, err := expandDisclosures(claims, disclosures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded := result.(JSON)
+	address := expanded["address"].(JSON)
+	if address["country"] != "DE" || address["locality"] != "Berlin" || address["_sd"] != nil {
+		t.Errorf("bad address: %v", address)
+	}
+	if nationalities := expanded["nationalities"].([]interface{}); len(nationalities) != 2 ||
+		nationalities[0] != "FR" || nationalities[1] != "DE" {
+		t.Errorf("bad nationalities: %v", nationalities)
+	}
+
+	// хеш не может использоваться дважды
+	for _, d := range disclosures {
+		d.used = false
+	}
+	claims["other"] = JSON{"_sd": []interface{}{sha256Base64(field)}}
+	if _, err := expandDisclosures(claims, disclosures); !errors.Is(err, ErrDisclosure) {
+		t.Error("reused digest accepted:", err)
+	}
+}
@@ -0,0 +1,274 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// SecurityEventType задает тип токена события безопасности в заголовке
+// (RFC 8417, раздел 2.3).
+const SecurityEventType = "secevent+jwt"
+
+// SecurityEvent описывает содержимое токена события безопасности (Security
+// Event Token, RFC 8417). Поле Events содержит описания событий по их
+// идентификаторам (URI). Субъект события указывается в описании события,
+// например в поле "subject" или "sub_id", а не на верхнем уровне токена.
+type SecurityEvent struct {
+	Claims
+	Events        map[string]JSON `json:"events"`           // описания событий
+	TransactionID string          `json:"txn,omitempty"`    // идентификатор транзакции
+	TimeOfEvent   Time            `json:"toe,omitempty"`    // время события
+	SubjectID     JSON            `json:"sub_id,omitempty"` // субъект (RFC 9493)
+}
+
+// SecurityEventConfig описывает шаблон для выпуска токенов событий
+// безопасности. Токены формируются так же, как это делает Config, но в
+// заголовке указывается тип "secevent+jwt", а время создания (iat) и
+// уникальный идентификатор (jti) добавляются всегда. Если UniqueID не
+// задан, то используется Nonce(16).
+//
+// Токены событий не должны быть похожи на токены доступа или ID-токены,
+// поэтому в них не допускаются поля exp и sub верхнего уровня (RFC 8417,
+// раздел 4.1).
+type SecurityEventConfig struct {
+	Config
+}
+
+// Token возвращает подписанный токен события безопасности. Токен должен
+// содержать хотя бы одно событие.
+func (c SecurityEventConfig) Token(event SecurityEvent) (string, error) {
+	if c.Key == nil {
+		return "", ErrEmptySignKey
+	}
+
+	conf := c.Config
+	conf.Created = true
+	if conf.UniqueID == nil {
+		conf.UniqueID = Nonce(16)
+	}
+
+	claims, err := conf.claims(event)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSecurityEvent(&Token{Claims: claims}); err != nil {
+		return "", err
+	}
+
+	return conf.encode(claims, &jwsHeader{Type: SecurityEventType})
+}
+
+// RequireSecurityEvent проверяет, что токен является токеном события
+// безопасности: в заголовке указан тип "secevent+jwt", заданы поля iss, iat,
+// jti и events, а поля exp и sub отсутствуют. Проверку издателя и
+// получателя нужно добавить отдельно с помощью IssuedBy и HasAudience.
+func RequireSecurityEvent() Validator {
+	return func(token *Token) error {
+		verr := new(ValidationError)
+		if !typeMatch(token.Header.Type, SecurityEventType) {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: "typ", Err: ErrBadType})
+		}
+		verr.merge(checkSecurityEvent(token))
+		if len(verr.Errors) > 0 {
+			return verr
+		}
+		return nil
+	}
+}
+
+// checkSecurityEvent проверяет поля токена события безопасности.
+func checkSecurityEvent(token *Token) error {
+	verr := new(ValidationError)
+	verr.merge(RequireClaims("iss", "iat", "jti")(token))
+	for _, name := range []string{"exp", "sub"} {
+		if _, ok := token.Claims[name]; ok {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: name,
+				Err: fmt.Errorf("%w: not allowed in security event", ErrBadClaim)})
+		}
+	}
+
+	// events должен быть объектом хотя бы с одним событием
+	switch events := token.Claims["events"].(type) {
+	case nil:
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "events", Err: ErrMissingClaim})
+	case JSON:
+		if len(events) == 0 {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: "events", Err: ErrMissingClaim})
+		}
+	case map[string]JSON:
+		if len(events) == 0 {
+			verr.add(ClaimsInvalid, &ClaimError{Claim: "events", Err: ErrMissingClaim})
+		}
+	default:
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "events", Err: ErrBadClaim})
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// VerifySecurityEvent проверяет токен события безопасности и возвращает его
+// содержимое. Ключ для проверки подписи обязателен. Издатель и получатель
+// токена проверяются с помощью validators.
+func VerifySecurityEvent(token string, key interface{}, validators ...Validator) (*SecurityEvent, error) {
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+
+	parsed, err := parse(token, key, SecurityEventType,
+		append([]Validator{RequireSecurityEvent()}, validators...))
+	if err != nil {
+		return nil, err
+	}
+
+	event := new(SecurityEvent)
+	if err := json.Unmarshal(parsed.Raw, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// SecurityEventReceiver принимает токены событий безопасности, доставляемые
+// с помощью HTTP POST (RFC 8935).
+//
+// Токен проверяется с помощью VerifySecurityEvent с ключом Key и
+// дополнительными проверками Validators, после чего передается в Handle.
+// Если токен принят, то возвращается ответ 202 Accepted, а в случае ошибки
+// проверки - 400 Bad Request с описанием ошибки в формате JSON
+// (RFC 8935, раздел 2.3). Подробности проверки отправителю не сообщаются.
+// Если ошибку возвращает Handle или не задан Key, то ответом будет
+// 500 Internal Server Error.
+type SecurityEventReceiver struct {
+	Key        interface{}                                           // ключ для проверки подписи или функция его возвращающая
+	Validators []Validator                                           // дополнительные проверки токена
+	Handle     func(ctx context.Context, event *SecurityEvent) error // обработчик событий
+}
+
+// maxSecurityEventSize ограничивает размер принимаемого токена события.
+const maxSecurityEventSize = 1 << 16
+
+// ServeHTTP обрабатывает запрос с токеном события безопасности.
+func (s *SecurityEventReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Key == nil {
+		// ошибка настройки получателя, а не токена
+		http.Error(w, http.StatusText(http.StatusInternalServerError),
+			http.StatusInternalServerError)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/secevent+jwt" {
+		setError(w, "invalid_request", "unsupported content type")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSecurityEventSize+1))
+	if err != nil {
+		setError(w, "invalid_request", "cannot read request body")
+		return
+	}
+	if len(body) > maxSecurityEventSize {
+		setError(w, "invalid_request", "security event too large")
+		return
+	}
+
+	event, err := VerifySecurityEvent(strings.TrimSpace(string(body)), s.Key, s.Validators...)
+	if err != nil {
+		code, description := setErrorCode(err)
+		setError(w, code, description)
+		return
+	}
+	if s.Handle != nil {
+		if err := s.Handle(r.Context(), event); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// setErrorCode возвращает код ошибки RFC 8935 для ошибки проверки токена и
+// ее описание для отправителя. Подробности проверки в описание не
+// попадают.
+func setErrorCode(err error) (code, description string) {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return "invalid_request", "invalid security event token"
+	}
+	if verr.Has(SignatureInvalid) {
+		return "invalid_key", "invalid signature"
+	}
+	for _, err := range verr.Errors {
+		var claimErr *ClaimError
+		if errors.As(err, &claimErr) {
+			switch claimErr.Claim {
+			case "iss":
+				return "invalid_issuer", "invalid issuer"
+			case "aud":
+				return "invalid_audience", "invalid audience"
+			}
+		}
+	}
+	return "invalid_request", "invalid security event token"
+}
+
+// setError отправляет ответ с ошибкой приема токена события в формате
+// RFC 8935, раздел 2.3.
+func setError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(struct {
+		Err         string `json:"err"`
+		Description string `json:"description,omitempty"`
+	}{code, description})
+}
+
+// PushSecurityEvent доставляет токен события безопасности получателю по
+// указанному адресу (RFC 8935). Если client не задан, то используется
+// http.DefaultClient. Если получатель отверг токен, то возвращается ошибка
+// с его кодом и описанием.
+func PushSecurityEvent(ctx context.Context, client *http.Client, endpoint, token string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint,
+		bytes.NewReader([]byte(token)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/secevent+jwt")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK:
+		return nil
+	case http.StatusBadRequest:
+		var result struct {
+			Err         string `json:"err"`
+			Description string `json:"description"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&result); err == nil && result.Err != "" {
+			return fmt.Errorf("security event rejected: %s: %s", result.Err, result.Description)
+		}
+	}
+	return fmt.Errorf("security event rejected: %s", resp.Status)
+}
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityEvent(t *testing.T) {
+	key := NewES256Key()
+	issuer := SecurityEventConfig{Config: Config{
+		Issuer: "https://idp.example.com/",
+		Key:    key,
+	}}
+	const sessionRevoked = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+
+	event := SecurityEvent{
+		Claims: Claims{Audience: Audience{"https://rp.example.com/"}},
+		Events: map[string]JSON{
+			sessionRevoked: {"event_timestamp": 1615304991643},
+		},
+		TransactionID: "8675309",
+		SubjectID:     JSON{"format": "email", "email": "user@example.com"},
+	}
+	token, err := issuer.Token(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received *SecurityEvent
+	server := httptest.NewServer(&SecurityEventReceiver{
+		Key: &key.PublicKey,
+		Validators: []Validator{
+			IssuedBy("https://idp.example.com/"),
+			HasAudience("https://rp.example.com/"),
+		},
+		Handle: func(ctx context.Context, event *SecurityEvent) error {
+			received = event
+			return nil
+		},
+	})
+	defer server.Close()
+	ctx, client := context.Background(), server.Client()
+
+	if err := PushSecurityEvent(ctx, client, server.URL, token); err != nil {
+		t.Fatal(err)
+	}
+	if received == nil || received.TransactionID != "8675309" ||
+		received.Events[sessionRevoked] == nil || received.ID == "" ||
+		received.SubjectID["email"] != "user@example.com" {
+		t.Errorf("bad event: %+v", received)
+	}
+
+	// ошибки приема
+	other := SecurityEventConfig{Config: Config{Issuer: "https://other.example.com/", Key: key}}
+	wrongIssuer, _ := other.Token(event)
+	forged, _ := SecurityEventConfig{Config: Config{
+		Issuer: "https://idp.example.com/", Key: NewES256Key()}}.Token(event)
+	plain, _ := Config{Issuer: "https://idp.example.com/", Key: key}.Token(JSON{
+		"events": JSON{sessionRevoked: JSON{}}, "aud": "https://rp.example.com/"})
+	for code, token := range map[string]string{
+		"invalid_issuer":  wrongIssuer,
+		"invalid_key":     forged,
+		"invalid_request": plain,
+	} {
+		err := PushSecurityEvent(ctx, client, server.URL, token)
+		if err == nil || !strings.Contains(err.Error(), code) {
+			t.Errorf("%s: unexpected error: %v", code, err)
+		}
+		// подробности проверки отправителю не сообщаются
+		if err != nil && strings.Contains(err.Error(), "example.com") {
+			t.Errorf("%s: verification details leaked: %v", code, err)
+		}
+	}
+
+	// без ключа токены отвергаются как ошибка настройки получателя
+	misconfigured := httptest.NewServer(&SecurityEventReceiver{})
+	defer misconfigured.Close()
+	if err := PushSecurityEvent(ctx, misconfigured.Client(), misconfigured.URL, token); err == nil ||
+		!strings.Contains(err.Error(), "500") {
+		t.Error("receiver without key:", err)
+	}
+
+	// поля, недопустимые в токене события
+	withExp := issuer
+	withExp.Expires = time.Hour
+	if _, err := withExp.Token(event); !errors.Is(err, ErrBadClaim) {
+		t.Error("security event with exp:", err)
+	}
+	withSub := event
+	withSub.Subject = "user"
+	if _, err := issuer.Token(withSub); !errors.Is(err, ErrBadClaim) {
+		t.Error("security event with sub:", err)
+	}
+	if _, err := issuer.Token(SecurityEvent{}); !errors.Is(err, ErrMissingClaim) {
+		t.Error("security event without events:", err)
+	}
+}
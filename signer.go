@@ -7,7 +7,6 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	_ "crypto/sha256" // импортируем явно для поддержки хеширования
-	"errors"
 	"fmt"
 	"math/big"
 )
@@ -126,7 +125,10 @@ repeat:
 	case *rsa.PublicKey: // проверяем подпись с публичным ключом RSA
 		h := hash.New()
 		_, _ = h.Write(data)
-		return rsa.VerifyPKCS1v15(signerKey, crypto.SHA256, h.Sum(nil), signature)
+		if err := rsa.VerifyPKCS1v15(signerKey, crypto.SHA256, h.Sum(nil), signature); err != nil {
+			return fmt.Errorf("%w: rsa", ErrBadSignature)
+		}
+		return nil
 
 	case *rsa.PrivateKey: // подменяем ключ на публичный
 		key = &signerKey.PublicKey
@@ -140,7 +142,7 @@ repeat:
 		r := new(big.Int).SetBytes(signature[:div])
 		s := new(big.Int).SetBytes(signature[div:])
 		if !ecdsa.Verify(signerKey, h.Sum(nil), r, s) {
-			return errors.New("bad ecdsa signature")
+			return fmt.Errorf("%w: ecdsa", ErrBadSignature)
 		}
 		return nil
 
@@ -153,7 +155,7 @@ repeat:
 		_, _ = mac.Write(data)
 		signature2 := mac.Sum(nil)
 		if !hmac.Equal(signature, signature2) {
-			return errors.New("bad hmac signature")
+			return fmt.Errorf("%w: hmac", ErrBadSignature)
 		}
 		return nil
 
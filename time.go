@@ -1,10 +1,25 @@
 package jwt
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// TimePrecision задает точность представления времени в формате JSON. По
+// умолчанию время представляется целым числом секунд. Если указать меньшее
+// значение, например time.Millisecond, то время будет представлено дробным
+// числом секунд с соответствующей точностью.
+var TimePrecision = time.Second
+
+// TimeLenient разрешает при разборе времени принимать числовые значения,
+// представленные в виде строки, например "1700000000". Некоторые провайдеры
+// токенов используют такой формат, хотя он и не соответствует RFC 7519.
+var TimeLenient = false
+
 // Time подменяет собой стандартное time.Time, но переопределяет для него
 // формат представления и распаковки из JSON в виде числа. Во всем остальном
 // ведет себя как стандартный time.Time.
@@ -13,17 +28,101 @@ type Time struct {
 }
 
 // UnmarshalJSON восстанавливает время, представленное в формате JSON в виде
-// числа.
+// числа. Число может быть дробным: в этом случае дробная часть задает доли
+// секунды. Значение null соответствует не заданному времени.
 func (t *Time) UnmarshalJSON(data []byte) error {
-	var i int64
-	if err := json.Unmarshal(data, &i); err != nil {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	// в нестрогом режиме принимаем числа в виде строки
+	if len(data) > 0 && data[0] == '"' {
+		if !TimeLenient {
+			return errors.New("jwt: time must be a number")
+		}
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(strings.TrimSpace(s))
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
 		return err
 	}
-	t.Time = time.Unix(i, 0)
+	tm, err := parseNumericDate(string(number))
+	if err != nil {
+		return err
+	}
+	t.Time = tm
 	return nil
 }
 
-// MarshalJSON представляет время в формате JSON в виде числа.
+// MarshalJSON представляет время в формате JSON в виде числа с точностью,
+// заданной в TimePrecision. Не заданное время представляется как null, а не
+// как отрицательное число секунд, соответствующее нулевому времени Go.
 func (t Time) MarshalJSON() ([]byte, error) {
-	return json.Marshal(t.Unix())
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	tm := t.Time
+	if TimePrecision > 0 {
+		tm = tm.Truncate(TimePrecision)
+	}
+
+	sec, nsec := tm.Unix(), int64(tm.Nanosecond())
+	if nsec == 0 || TimePrecision >= time.Second {
+		return json.Marshal(sec)
+	}
+
+	// для времени до 1970 года дробная часть отсчитывается в обратную сторону
+	sign := ""
+	if sec < 0 {
+		sign, sec, nsec = "-", -(sec + 1), int64(time.Second)-nsec
+	}
+	fraction := strings.TrimRight(strconv.FormatInt(nsec+int64(time.Second), 10)[1:], "0")
+	return []byte(sign + strconv.FormatInt(sec, 10) + "." + fraction), nil
+}
+
+// parseNumericDate разбирает число секунд с возможной дробной частью без
+// потери точности, свойственной числам с плавающей точкой.
+func parseNumericDate(s string) (time.Time, error) {
+	if strings.ContainsAny(s, "eE") {
+		// экспоненциальная запись: точности float64 достаточно
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*float64(time.Second))), nil
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	intPart, fracPart := s, ""
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		intPart, fracPart = s[:idx], s[idx+1:]
+	}
+	sec, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var nsec int64
+	if fracPart != "" {
+		if len(fracPart) > 9 {
+			fracPart = fracPart[:9] // точнее наносекунд время не хранится
+		}
+		fracPart += strings.Repeat("0", 9-len(fracPart))
+		if nsec, err = strconv.ParseInt(fracPart, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+		if negative {
+			nsec = -nsec
+		}
+	}
+
+	return time.Unix(sec, nsec), nil
 }
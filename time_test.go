@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimeUnmarshal(t *testing.T) {
+	for data, expected := range map[string]time.Time{
+		`1700000000`:           time.Unix(1700000000, 0),
+		`1700000000.5`:         time.Unix(1700000000, 500000000),
+		`1700000000.123456789`: time.Unix(1700000000, 123456789),
+		`1.7e9`:                time.Unix(1700000000, 0),
+		`-1.5`:                 time.Unix(-1, -500000000),
+		`null`:                 {},
+	} {
+		var tm Time
+		if err := json.Unmarshal([]byte(data), &tm); err != nil {
+			t.Fatal(data, err)
+		}
+		if !tm.Equal(expected) {
+			t.Errorf("bad time %s: %v", data, tm)
+		}
+	}
+
+	var tm Time
+	if err := json.Unmarshal([]byte(`"1700000000"`), &tm); err == nil {
+		t.Error("string time accepted in strict mode")
+	}
+
+	defer func() { TimeLenient = false }()
+	TimeLenient = true
+	if err := json.Unmarshal([]byte(`"1700000000.25"`), &tm); err != nil {
+		t.Fatal(err)
+	}
+	if !tm.Equal(time.Unix(1700000000, 250000000)) {
+		t.Errorf("bad lenient time: %v", tm)
+	}
+}
+
+func TestTimeMarshal(t *testing.T) {
+	tm := Time{Time: time.Unix(1700000000, 123456789)}
+	if data, _ := json.Marshal(tm); string(data) != "1700000000" {
+		t.Errorf("bad time: %s", data)
+	}
+
+	defer func() { TimePrecision = time.Second }()
+	TimePrecision = time.Millisecond
+	for value, expected := range map[Time]string{
+		tm:                                    "1700000000.123",
+		{Time: time.Unix(1700000000, 0)}:      "1700000000",
+		{Time: time.Unix(-2, 500000000)}:      "-1.5",
+		{Time: time.Unix(1700000000, 500000)}: "1700000000",
+	} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("bad time %v: %s", value, data)
+		}
+		var restored Time
+		if err := json.Unmarshal(data, &restored); err != nil {
+			t.Fatal(err)
+		}
+		if !restored.Equal(value.Truncate(time.Millisecond)) {
+			t.Errorf("bad restored time: %v", restored)
+		}
+	}
+}
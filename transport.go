@@ -0,0 +1,103 @@
+package jwt
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Transport реализует http.RoundTripper, который добавляет к каждому запросу
+// заголовок Authorization с токеном, сгенерированным по шаблону Config.
+//
+// Для каждого запроса в токен добавляется получатель (aud), который по
+// умолчанию определяется схемой и адресом сервера из URL запроса. Изменить
+// это можно, задав функцию Audience. Если она возвращает пустую строку, то
+// получатель в токен не добавляется.
+//
+// Если задано Cache, то сгенерированный токен повторно используется для
+// запросов к тому же получателю, пока до окончания его действия не останется
+// меньше RenewBefore (по умолчанию 10 секунд). Иначе для каждого запроса
+// генерируется новый токен.
+//
+// Безопасен для одновременного использования.
+type Transport struct {
+	Config      Config                       // шаблон для генерации токенов
+	Base        http.RoundTripper            // по умолчанию http.DefaultTransport
+	Audience    func(r *http.Request) string // получатель токена для запроса
+	Cache       bool                         // повторно использовать токены
+	RenewBefore time.Duration                // запас времени до окончания действия
+
+	mu     sync.Mutex
+	tokens map[string]cachedToken // токены по получателям
+}
+
+// cachedToken описывает сохраненный токен.
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// RoundTrip добавляет к запросу токен и выполняет его.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	audience := r.URL.Scheme + "://" + r.URL.Host
+	if t.Audience != nil {
+		audience = t.Audience(r)
+	}
+
+	token, err := t.token(audience)
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close() // RoundTripper всегда должен закрывать тело запроса
+		}
+		return nil, err
+	}
+
+	// исходный запрос изменять нельзя, поэтому работаем с копией
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
+
+// token возвращает токен для указанного получателя.
+func (t *Transport) token(audience string) (string, error) {
+	claimset := make(JSON)
+	if audience != "" {
+		claimset["aud"] = audience
+	}
+	if !t.Cache {
+		return t.Config.Token(claimset)
+	}
+
+	renew := t.RenewBefore
+	if renew <= 0 {
+		renew = 10 * time.Second
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if cached, ok := t.tokens[audience]; ok &&
+		(cached.expires.IsZero() || cached.expires.Sub(now) > renew) {
+		return cached.token, nil
+	}
+
+	token, err := t.Config.Token(claimset)
+	if err != nil {
+		return "", err
+	}
+	var expires time.Time
+	if t.Config.Expires > 0 {
+		expires = now.Add(t.Config.Expires)
+	}
+	if t.tokens == nil {
+		t.tokens = make(map[string]cachedToken)
+	}
+	t.tokens[audience] = cachedToken{token: token, expires: expires}
+	return token, nil
+}
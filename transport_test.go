@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	key := NewES256Key()
+	var (
+		mu     sync.Mutex
+		tokens = make(map[string]bool)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := TokenFromHeader(r)
+		if _, err := Verify(token, key, HasAudience("http://"+r.Host)); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		tokens[token] = true
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	transport := &Transport{
+		Config: Config{
+			Issuer:   "client",
+			Expires:  time.Minute,
+			UniqueID: Nonce(16),
+			Key:      key,
+		},
+		Base:  server.Client().Transport,
+		Cache: true,
+	}
+	client := &http.Client{Transport: transport}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Error("bad status:", resp.Status)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(tokens) != 1 {
+		t.Errorf("cached token not reused: %d tokens", len(tokens))
+	}
+
+	// без кеширования токен генерируется для каждого запроса
+	transport.Cache = false
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(tokens) != 3 {
+		t.Errorf("fresh token not generated: %d tokens", len(tokens))
+	}
+
+	// токен обновляется незадолго до окончания действия
+	transport.Cache = true
+	transport.RenewBefore = 2 * time.Minute
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(tokens) != 4 {
+		t.Errorf("expiring token not renewed: %d tokens", len(tokens))
+	}
+}
@@ -0,0 +1,50 @@
+package jwt
+
+import "encoding/json"
+
+// VerifyClaims проверяет подпись и основные даты токена так же, как это
+// делает Verify, и возвращает его содержимое, распакованное в объект
+// указанного типа. В отличие от Verify, ключ для проверки подписи обязателен.
+// Дополнительные проверки содержимого токена задаются в validators.
+//
+//	claims, err := jwt.VerifyClaims[MyClaims](token, key)
+func VerifyClaims[T any](token string, key interface{}, validators ...Validator) (*T, error) {
+	if key == nil {
+		return nil, ErrEmptySignKey
+	}
+
+	claim, err := Verify(token, key, validators...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := new(T)
+	if err := json.Unmarshal(claim, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// TypedConfig описывает шаблон для генерации токенов с содержимым
+// определенного типа. Он ведет себя так же, как Config, но проверяет тип
+// передаваемых данных на этапе компиляции.
+//
+//	issuer := jwt.TypedConfig[MyClaims]{Config: conf}
+//	token, err := issuer.Token(MyClaims{...})
+type TypedConfig[T any] struct {
+	Config
+}
+
+// Token возвращает сгенерированный токен на основании шаблона и
+// предоставленных данных. Правила формирования токена описаны в Config.Token.
+func (c TypedConfig[T]) Token(claimset T) (string, error) {
+	return c.Config.Token(claimset)
+}
+
+// Verify проверяет токен и возвращает его содержимое, распакованное в объект
+// того же типа, что используется при генерации токенов. В качестве ключа
+// передается ключ для проверки подписи в любом из форматов, поддерживаемых
+// Verify.
+func (c TypedConfig[T]) Verify(token string, key interface{}, validators ...Validator) (*T, error) {
+	return VerifyClaims[T](token, key, validators...)
+}
@@ -0,0 +1,46 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypedConfig(t *testing.T) {
+	type userClaims struct {
+		Claims
+		Email string `json:"email"`
+	}
+
+	key := NewES256Key()
+	issuer := TypedConfig[userClaims]{Config: Config{
+		Issuer:   "http://service.example.com/",
+		Expires:  time.Hour,
+		UniqueID: Nonce(8),
+		Key:      key,
+	}}
+
+	token, err := issuer.Token(userClaims{
+		Claims: Claims{Subject: "9394203942934"},
+		Email:  "user@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := issuer.Verify(token, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "9394203942934" || claims.Email != "user@example.com" ||
+		claims.Issuer != "http://service.example.com/" || claims.ID == "" ||
+		claims.Expires.IsZero() {
+		t.Errorf("bad claims: %+v", claims)
+	}
+
+	if _, err := VerifyClaims[userClaims](token, nil); err != ErrEmptySignKey {
+		t.Error("verified without key:", err)
+	}
+	if _, err := VerifyClaims[userClaims](token, NewES256Key()); err == nil {
+		t.Error("verified with wrong key")
+	}
+}
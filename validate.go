@@ -0,0 +1,231 @@
+package jwt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Header описывает заголовок подписанного токена.
+type Header struct {
+	Algorithm   string `json:"alg"`             // алгоритм подписи
+	Type        string `json:"typ,omitempty"`   // тип токена
+	ContentType string `json:"cty,omitempty"`   // тип содержимого
+	KeyID       string `json:"kid,omitempty"`   // необязательный идентификатор ключа
+	JWK         *JWK   `json:"jwk,omitempty"`   // открытый ключ, которым подписан токен
+	Nonce       string `json:"nonce,omitempty"` // nonce сервера ACME
+	URL         string `json:"url,omitempty"`   // адрес запроса ACME
+}
+
+// Token описывает разобранный токен, который передается для дополнительной
+// проверки в Validator.
+type Token struct {
+	Header Header // заголовок токена
+	Claims JSON   // разобранное содержимое токена
+	Raw    []byte // неразобранное содержимое токена
+
+	validating bool           // выполняются проверки validate
+	deferred   []func() error // действия после успешных проверок
+}
+
+// String возвращает строковое значение поля токена. Если поле не задано или
+// не является строкой, то возвращается пустая строка.
+func (t *Token) String(name string) string {
+	s, _ := t.Claims[name].(string)
+	return s
+}
+
+// Time возвращает значение поля токена, содержащего дату в числовом виде.
+// Если поле не задано или не является числом, то возвращается нулевое время.
+func (t *Token) Time(name string) time.Time {
+	value, ok := t.Claims[name].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	sec := int64(value)
+	return time.Unix(sec, int64((value-float64(sec))*float64(time.Second)))
+}
+
+// Validator описывает функцию дополнительной проверки содержимого токена.
+// Ошибки, которые она возвращает, попадают в *ValidationError с категорией
+// ClaimsInvalid. Для описания ошибки в отдельном поле лучше возвращать
+// *ClaimError.
+type Validator func(token *Token) error
+
+// All объединяет несколько проверок в одну. Выполняются все проверки, а
+// возвращаемая ошибка содержит все обнаруженные проблемы.
+func All(validators ...Validator) Validator {
+	return func(token *Token) error {
+		verr := new(ValidationError)
+		token.validate(verr, validators)
+		if len(verr.Errors) > 0 {
+			return verr
+		}
+		return nil
+	}
+}
+
+// validate выполняет проверки и добавляет их ошибки в verr. Действия с
+// побочными эффектами, отложенные проверками с помощью afterValidation
+// (например, запоминание идентификатора в PreventReplay), выполняются после
+// всех проверок и только если токен прошел их все, независимо от порядка
+// проверок. При вложенном вызове (из All) их выполняет внешний вызов.
+func (t *Token) validate(verr *ValidationError, validators []Validator) {
+	if t.validating {
+		for _, validator := range validators {
+			verr.merge(validator(t))
+		}
+		return
+	}
+
+	t.validating = true
+	for _, validator := range validators {
+		verr.merge(validator(t))
+	}
+	deferred := t.deferred
+	t.validating, t.deferred = false, nil
+	for _, action := range deferred {
+		if len(verr.Errors) > 0 {
+			return
+		}
+		verr.merge(action())
+	}
+}
+
+// afterValidation откладывает действие с побочными эффектами до окончания
+// всех проверок токена. Если проверка вызвана не из validate, то действие
+// выполняется сразу.
+func (t *Token) afterValidation(action func() error) error {
+	if t.validating {
+		t.deferred = append(t.deferred, action)
+		return nil
+	}
+	return action()
+}
+
+// RequireClaims проверяет, что в токене заданы все указанные поля.
+func RequireClaims(names ...string) Validator {
+	return func(token *Token) error {
+		verr := new(ValidationError)
+		for _, name := range names {
+			if value, ok := token.Claims[name]; !ok || value == nil {
+				verr.add(ClaimsInvalid, &ClaimError{Claim: name, Err: ErrMissingClaim})
+			}
+		}
+		if len(verr.Errors) > 0 {
+			return verr
+		}
+		return nil
+	}
+}
+
+// CheckClaim проверяет значение поля токена с помощью функции check. Если
+// поле не задано, то возвращается ошибка ErrMissingClaim. Ошибка, которую
+// возвращает check, сохраняется в *ClaimError вместе с названием поля.
+func CheckClaim(name string, check func(value interface{}) error) Validator {
+	return func(token *Token) error {
+		value, ok := token.Claims[name]
+		if !ok || value == nil {
+			return &ClaimError{Claim: name, Err: ErrMissingClaim}
+		}
+		if err := check(value); err != nil {
+			return &ClaimError{Claim: name, Err: err}
+		}
+		return nil
+	}
+}
+
+// ClaimEquals проверяет, что поле токена содержит указанную строку.
+func ClaimEquals(name, value string) Validator {
+	return CheckClaim(name, func(v interface{}) error {
+		if v != value {
+			return fmt.Errorf("%w: %v", ErrBadClaim, v)
+		}
+		return nil
+	})
+}
+
+// ClaimContains проверяет, что поле токена содержит все указанные значения.
+// Поле может быть массивом строк или строкой со значениями, разделенными
+// пробелами, как это принято для "scope".
+func ClaimContains(name string, values ...string) Validator {
+	return CheckClaim(name, func(v interface{}) error {
+		list := claimStrings(v)
+	next:
+		for _, value := range values {
+			for _, item := range list {
+				if item == value {
+					continue next
+				}
+			}
+			return fmt.Errorf("%w: %q not found", ErrBadClaim, value)
+		}
+		return nil
+	})
+}
+
+// IssuedBy проверяет, что токен выпущен одним из указанных издателей (iss).
+func IssuedBy(issuers ...string) Validator {
+	return CheckClaim("iss", func(v interface{}) error {
+		for _, issuer := range issuers {
+			if v == issuer {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %v", ErrBadClaim, v)
+	})
+}
+
+// HasAudience проверяет, что среди получателей токена (aud) есть указанный.
+// Поле aud может быть как строкой, так и массивом строк.
+func HasAudience(audience string) Validator {
+	return CheckClaim("aud", func(v interface{}) error {
+		for _, aud := range claimAudience(v) {
+			if aud == audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %v", ErrBadClaim, v)
+	})
+}
+
+// AllowAlgorithms проверяет, что токен подписан одним из указанных
+// алгоритмов.
+func AllowAlgorithms(algorithms ...string) Validator {
+	return func(token *Token) error {
+		for _, alg := range algorithms {
+			if token.Header.Algorithm == alg {
+				return nil
+			}
+		}
+		return &ClaimError{Claim: "alg",
+			Err: fmt.Errorf("%w: %q", ErrBadClaim, token.Header.Algorithm)}
+	}
+}
+
+// claimAudience возвращает список получателей из значения поля aud.
+func claimAudience(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// claimStrings возвращает список строк из массива или строки со значениями,
+// разделенными пробелами.
+func claimStrings(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return strings.Fields(s)
+	}
+	return claimAudience(v)
+}
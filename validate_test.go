@@ -0,0 +1,77 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyValidators(t *testing.T) {
+	conf := Config{
+		Issuer:   "http://service.example.com/",
+		Expires:  time.Hour,
+		UniqueID: Nonce(8),
+		Key:      "secret",
+	}
+	token, err := conf.Token(JSON{
+		"sub":       "9394203942934",
+		"aud":       []string{"api", "web"},
+		"scope":     "read write",
+		"tenant_id": "acme",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Verify(token, "secret",
+		RequireClaims("jti", "sub", "tenant_id"),
+		IssuedBy("http://service.example.com/"),
+		HasAudience("api"),
+		ClaimContains("scope", "read"),
+		ClaimEquals("tenant_id", "acme"),
+		AllowAlgorithms("HS256"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Verify(token, "secret",
+		RequireClaims("jti", "email"),
+		All(HasAudience("admin"), ClaimContains("scope", "read", "delete")),
+		CheckClaim("tenant_id", func(value interface{}) error {
+			if value != "other" {
+				return errors.New("unknown tenant")
+			}
+			return nil
+		}),
+	)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Kind != ClaimsInvalid {
+		t.Fatalf("bad error: %v", err)
+	}
+	claims := make(map[string]bool)
+	for _, err := range verr.Errors {
+		var claimErr *ClaimError
+		if !errors.As(err, &claimErr) {
+			t.Fatalf("bad claim error type: %T", err)
+		}
+		claims[claimErr.Claim] = true
+	}
+	for _, name := range []string{"email", "aud", "scope", "tenant_id"} {
+		if !claims[name] {
+			t.Errorf("missing error for %q", name)
+		}
+	}
+	if !errors.Is(err, ErrMissingClaim) || !errors.Is(err, ErrBadClaim) {
+		t.Error("bad error reasons:", err)
+	}
+
+	// при неверной подписи дополнительные проверки не выполняются
+	called := false
+	_, err = Verify(token, "wrong secret", func(*Token) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Error("validator called for bad signature")
+	}
+}
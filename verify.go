@@ -3,6 +3,8 @@ package jwt
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -24,21 +26,50 @@ import (
 // Так же поддерживаются следующие форматы функции для передачи ключа:
 // 	func(keyID string, alg string) interface{}
 // 	func(keyID string) interface{}
+// 	func(header *Header) interface{}
+//
+// Последний вариант получает весь заголовок токена, что позволяет, например,
+// использовать открытый ключ из поля "jwk", если он заслуживает доверия.
 //
 // Кроме проверки подписи, проверяются основные даты токена, что он актуален
 // на данный момент.
 //
-// Возвращается неразобранное содержимое токена.
-func Verify(token string, key interface{}) (claim []byte, err error) {
+// Дополнительные проверки содержимого токена можно задать в validators. Они
+// вызываются только после успешной проверки подписи (или если ключ не задан).
+//
+// Возвращается неразобранное содержимое токена. В случае ошибки возвращается
+// *ValidationError, в котором собраны все обнаруженные проблемы: ошибки
+// разбора токена, ошибки подписи и ошибки проверки полей.
+func Verify(token string, key interface{}, validators ...Validator) (claim []byte, err error) {
+	parsed, err := parse(token, key, "", validators)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Raw, nil
+}
+
+// parse разбирает и проверяет токен так же, как это делает Verify, и
+// возвращает его в разобранном виде. Если typ задан, то токен должен иметь
+// в заголовке именно этот тип.
+func parse(token string, key interface{}, typ string, validators []Validator) (*Token, error) {
+	verr := new(ValidationError)
+	malformed := func(err error) (*Token, error) {
+		if err != ErrInvalid && err != ErrBadType {
+			err = fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		verr.add(Malformed, err)
+		return nil, verr
+	}
+
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return nil, ErrInvalid
+		return malformed(ErrInvalid)
 	}
 
 	// разбираем основной раздел токена
-	claim, err = base64.RawURLEncoding.DecodeString(parts[1])
+	claim, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, err
+		return malformed(err)
 	}
 
 	times := new(struct {
@@ -47,52 +78,122 @@ func Verify(token string, key interface{}) (claim []byte, err error) {
 		NotBefore Time `json:"nbf"`
 	})
 	if err := json.Unmarshal(claim, times); err != nil {
-		return nil, err
-	}
-
-	// проверяем поля со временем
-	now := time.Now() // текущее время
-	if !times.Created.IsZero() && times.Created.After(now) {
-		return nil, ErrCreatedAfterNow
-	}
-	if !times.Expires.IsZero() && times.Expires.Before(now) {
-		return nil, ErrExpired
+		return malformed(err)
 	}
-	if !times.NotBefore.IsZero() && times.NotBefore.After(now) {
-		return nil, ErrNotBeforeNow
+	parsed := &Token{Raw: claim}
+	if err := json.Unmarshal(claim, &parsed.Claims); err != nil {
+		return malformed(err)
 	}
 
 	// разбираем заголовок токена
 	data, err := base64.RawURLEncoding.DecodeString(parts[0])
 	if err != nil {
-		return nil, err
+		return malformed(err)
 	}
 
-	header := new(struct {
-		Algorithm string `json:"alg"`           // алгоритм подписи
-		Type      string `json:"typ"`           // тип токена
-		KeyID     string `json:"kid,omitempty"` // необязательный идентификатор ключа
-	})
+	header := &parsed.Header
 	if err := json.Unmarshal(data, header); err != nil {
-		return nil, err
+		return malformed(err)
 	}
 
 	// проверяем тип токена
-	if header.Type != "" && header.Type != "JWT" {
-		return nil, ErrBadType
+	if !typeMatch(header.Type, typ) {
+		return malformed(ErrBadType)
+	}
+
+	// проверяем поля со временем
+	checkTimes(verr, times.Created.Time, times.Expires.Time, times.NotBefore.Time)
+
+	// проверяем подпись токена
+	if err := verifySignature(token, parts, header, key); err != nil {
+		if err != errSkipSignature {
+			verr.add(SignatureInvalid, err)
+		}
+	}
+
+	// дополнительные проверки выполняются только для токенов с верной
+	// подписью, так как они могут изменять состояние (например, запоминать
+	// идентификатор токена)
+	if !verr.Has(SignatureInvalid) {
+		parsed.validate(verr, validators)
+	}
+
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return parsed, nil
+}
+
+// checkTimes проверяет, что токен актуален на текущий момент по времени
+// создания, окончания и начала действия. Не заданное время не проверяется.
+func checkTimes(verr *ValidationError, created, expires, notBefore time.Time) {
+	now := time.Now() // текущее время
+	if !created.IsZero() && created.After(now) {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "iat",
+			Time: created, Now: now, Err: ErrCreatedAfterNow})
 	}
+	if !expires.IsZero() && expires.Before(now) {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "exp",
+			Time: expires, Now: now, Err: ErrExpired})
+	}
+	if !notBefore.IsZero() && notBefore.After(now) {
+		verr.add(ClaimsInvalid, &ClaimError{Claim: "nbf",
+			Time: notBefore, Now: now, Err: ErrNotBeforeNow})
+	}
+}
+
+// typeMatch проверяет тип токена в заголовке. Если ожидаемый тип не задан,
+// то допускается только "JWT" или отсутствие типа. Иначе тип обязателен и
+// сравнивается без учета регистра и префикса "application/" (RFC 7515,
+// раздел 4.1.9).
+func typeMatch(typ, expected string) bool {
+	if expected == "" {
+		return typ == "" || typ == "JWT"
+	}
+	typ = strings.TrimPrefix(strings.ToLower(typ), "application/")
+	return typ == strings.ToLower(expected)
+}
+
+// errSkipSignature возвращается, если ключ для проверки подписи не задан и
+// проверка не требуется.
+var errSkipSignature = errors.New("signature verification skipped")
+
+// verifySignature проверяет подпись токена. Если ключ не задан, то
+// возвращается errSkipSignature.
+func verifySignature(token string, parts []string, header *Header, key interface{}) error {
 	if len(parts[2]) == 0 {
-		return nil, ErrNotSigned
+		return ErrNotSigned
+	}
+
+	key, err := verifyKey(header, key)
+	if err != nil {
+		return err
+	}
+
+	// декодируем подпись
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
 	}
 
-	// если для получения ключа задана функция, то вызываем ее
+	// проверяем подпись токена
+	withoutSignature := token[:len(parts[0])+len(parts[1])+1]
+	return verify([]byte(withoutSignature), signature, key)
+}
+
+// verifyKey возвращает ключ для проверки подписи. Если для получения ключа
+// задана функция, то она вызывается с данными из заголовка. Если ключ не
+// задан, то возвращается errSkipSignature.
+func verifyKey(header *Header, key interface{}) (interface{}, error) {
 	switch fkey := key.(type) {
 	case nil:
-		return claim, nil // проверка не требуется
+		return nil, errSkipSignature // проверка не требуется
 	case func(string, string) interface{}:
 		key = fkey(header.Algorithm, header.KeyID)
 	case func(string) interface{}:
 		key = fkey(header.Algorithm)
+	case func(*Header) interface{}:
+		key = fkey(header)
 	}
 
 	if key == nil {
@@ -100,17 +201,5 @@ func Verify(token string, key interface{}) (claim []byte, err error) {
 	} else if err, ok := key.(error); ok {
 		return nil, err
 	}
-
-	// декодируем подпись
-	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
-	if err != nil {
-		return nil, err
-	}
-
-	// проверяем подпись токена
-	withoutSignature := token[:len(parts[0])+len(parts[1])+1]
-	if err = verify([]byte(withoutSignature), signature, key); err != nil {
-		return nil, err
-	}
-	return claim, nil
+	return key, nil
 }
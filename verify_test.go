@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -49,3 +50,42 @@ func TestVerifyNotSignedToken(t *testing.T) {
 		t.Fatal("bad verify unsigned token")
 	}
 }
+
+func TestVerifyValidationError(t *testing.T) {
+	key := NewES256Key()
+	token, err := Encode(JSON{
+		"sub": "9394203942934",
+		"exp": Time{Time: time.Now().Add(-time.Hour)},
+		"nbf": Time{Time: time.Now().Add(time.Hour)},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Verify(token, NewES256Key())
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("bad error type: %T", err)
+	}
+	if !verr.Has(SignatureInvalid) || !verr.Has(ClaimsInvalid) || verr.Has(Malformed) {
+		t.Errorf("bad error kind: %v", verr.Kind)
+	}
+	if len(verr.Errors) != 3 {
+		t.Errorf("bad errors count: %v", err)
+	}
+	for _, target := range []error{ErrExpired, ErrNotBeforeNow, ErrBadSignature} {
+		if !errors.Is(err, target) {
+			t.Errorf("missing %q", target)
+		}
+	}
+
+	var claimErr *ClaimError
+	if !errors.As(err, &claimErr) || claimErr.Claim != "exp" || claimErr.Time.IsZero() {
+		t.Errorf("bad claim error: %+v", claimErr)
+	}
+
+	if _, err := Verify("bad.token", key); !errors.Is(err, ErrInvalid) ||
+		!errors.As(err, &verr) || verr.Kind != Malformed {
+		t.Errorf("bad malformed token error: %v", err)
+	}
+}